@@ -0,0 +1,60 @@
+package gocassa
+
+import (
+	"bytes"
+)
+
+// CASResult is filled in by conditional writes (lightweight transactions, compare-and-set in Cassandra terminology)
+// after they are run.
+type CASResult struct {
+	// Applied reports whether the write took place
+	Applied bool
+	// Current holds the values of the existing row which made the condition fail. It is empty if the write was
+	// applied or the row does not exist.
+	Current map[string]interface{}
+}
+
+// Decode decodes the current values of the row into the pointer.
+func (r *CASResult) Decode(pointer interface{}) error {
+	return decodeResult(r.Current, pointer)
+}
+
+// casCondition holds the condition of a lightweight transaction
+type casCondition struct {
+	notExists bool
+	// conditions are the IF relations of an update or delete, if empty the row only has to exist
+	conditions []Relation
+	result     *CASResult
+}
+
+func (c *casCondition) setResult(applied bool, current map[string]interface{}) {
+	if c.result == nil {
+		return
+	}
+	if applied || current == nil {
+		current = map[string]interface{}{}
+	}
+	c.result.Applied = applied
+	c.result.Current = current
+}
+
+// IF NOT EXISTS, IF EXISTS or IF col1 = ? AND col2 > ?
+func (c *casCondition) cql() (string, []interface{}) {
+	switch {
+	case c.notExists:
+		return " IF NOT EXISTS", []interface{}{}
+	case len(c.conditions) == 0:
+		return " IF EXISTS", []interface{}{}
+	}
+
+	buf := new(bytes.Buffer)
+	buf.WriteString(" IF ")
+	for i, r := range c.conditions {
+		if i > 0 {
+			buf.WriteString(" AND ")
+		}
+		s, _ := r.cql()
+		buf.WriteString(s)
+	}
+	return buf.String(), relationValues(c.conditions)
+}
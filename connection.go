@@ -29,9 +29,47 @@ func NewConnection(q QueryExecutor) Connection {
 	}
 }
 
-// CreateKeySpace creates a keyspace with the given name. Only used to create test keyspaces.
+// CreateKeySpace creates a keyspace with the given name, replicated with SimpleStrategy and a replication factor
+// of 1. Mostly used to create test keyspaces, see CreateKeySpaceWithOptions.
 func (c *connection) CreateKeySpace(name string) error {
-	stmt := fmt.Sprintf("CREATE KEYSPACE %s WITH replication = {'class': 'SimpleStrategy', 'replication_factor': 1 };", name)
+	return c.CreateKeySpaceWithOptions(name, KeySpaceOptions{})
+}
+
+// CreateKeySpaceWithOptions creates a keyspace with the given name and replication.
+// If the keyspace already exists, it returns an error.
+func (c *connection) CreateKeySpaceWithOptions(name string, opts KeySpaceOptions) error {
+	stmt, err := keySpaceStmt("CREATE KEYSPACE", name, opts)
+	if err != nil {
+		return err
+	}
+	return c.q.Execute(stmt)
+}
+
+// CreateKeySpaceIfNotExists creates a keyspace with the given name and replication, but only if it does not exist
+// already. The replication of an existing keyspace is left alone, see AlterKeySpace.
+func (c *connection) CreateKeySpaceIfNotExists(name string, opts KeySpaceOptions) error {
+	stmt, err := keySpaceStmt("CREATE KEYSPACE IF NOT EXISTS", name, opts)
+	if err != nil {
+		return err
+	}
+	return c.q.Execute(stmt)
+}
+
+// AlterKeySpace changes the replication of the keyspace having the given name. Data is not moved to the new
+// replicas until a repair is run. The replication is not defaulted as it is on creation, so that a production
+// keyspace is not altered to a single replica by mistake: either Strategy or DatacenterRF must be set, and
+// SimpleStrategy needs a ReplicationFactor.
+func (c *connection) AlterKeySpace(name string, opts KeySpaceOptions) error {
+	if opts.Strategy == "" && len(opts.DatacenterRF) == 0 {
+		return fmt.Errorf("Altering keyspace %s needs either a replication strategy or replication factors per datacenter", name)
+	}
+	if opts.Strategy == SimpleStrategy && opts.ReplicationFactor <= 0 {
+		return fmt.Errorf("Altering keyspace %s with %s needs a replication factor", name, SimpleStrategy)
+	}
+	stmt, err := keySpaceStmt("ALTER KEYSPACE", name, opts)
+	if err != nil {
+		return err
+	}
 	return c.q.Execute(stmt)
 }
 
@@ -44,13 +82,20 @@ func (c *connection) DropKeySpace(name string) error {
 // KeySpace returns the keyspace having the given name.
 func (c *connection) KeySpace(name string) KeySpace {
 	k := &k{
-		qe:   c.q,
-		name: name,
+		qe:     c.q,
+		name:   name,
+		schema: &schemaFlavour{},
 	}
 	k.tableFactory = k
 	return k
 }
 
+func (c *connection) WithQueryObserver(observer QueryObserver) Connection {
+	return &connection{
+		q: ObserveQueryExecutor(c.q, observer),
+	}
+}
+
 // Close closes the current session
 // The connection should not be used again after calling Close()
 func (c *connection) Close() {
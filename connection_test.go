@@ -0,0 +1,62 @@
+package gocassa
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeySpaceStatements(t *testing.T) {
+	stmts := []string{}
+	conn := NewConnection(StatementRecordingQE{OptionCheckingQE: OptionCheckingQE{opts: &Options{}}, stmts: &stmts})
+	durable := false
+	if err := conn.CreateKeySpace("test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.CreateKeySpaceWithOptions("events", KeySpaceOptions{ReplicationFactor: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.CreateKeySpaceIfNotExists("events", KeySpaceOptions{
+		DatacenterRF:  map[string]int{"us-east-1": 2, "eu-west-1": 3},
+		DurableWrites: &durable,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.AlterKeySpace("events", KeySpaceOptions{Strategy: NetworkTopologyStrategy, DatacenterRF: map[string]int{"eu-west-1": 5}}); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"CREATE KEYSPACE test WITH replication = {'class': 'SimpleStrategy', 'replication_factor': 1}",
+		"CREATE KEYSPACE events WITH replication = {'class': 'SimpleStrategy', 'replication_factor': 3}",
+		"CREATE KEYSPACE IF NOT EXISTS events WITH replication = {'class': 'NetworkTopologyStrategy', 'eu-west-1': 3, 'us-east-1': 2} AND durable_writes = false",
+		"ALTER KEYSPACE events WITH replication = {'class': 'NetworkTopologyStrategy', 'eu-west-1': 5}",
+	}
+	if !reflect.DeepEqual(stmts, expected) {
+		t.Fatal(stmts)
+	}
+
+	for _, opts := range []KeySpaceOptions{
+		{Strategy: NetworkTopologyStrategy},
+		{Strategy: SimpleStrategy, DatacenterRF: map[string]int{"eu-west-1": 3}},
+		{Strategy: "LocalStrategy"},
+	} {
+		if err := conn.CreateKeySpaceWithOptions("events", opts); err == nil {
+			t.Errorf("Expected an error for %+v", opts)
+		}
+	}
+
+	// The replication of an altered keyspace is not defaulted
+	for _, opts := range []KeySpaceOptions{{}, {ReplicationFactor: 3}, {Strategy: SimpleStrategy}} {
+		if err := conn.AlterKeySpace("events", opts); err == nil {
+			t.Errorf("Expected an error altering the keyspace with %+v", opts)
+		}
+	}
+	if len(stmts) != 4 {
+		t.Fatal(stmts)
+	}
+	if err := conn.AlterKeySpace("test", KeySpaceOptions{Strategy: SimpleStrategy, ReplicationFactor: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if stmts[4] != "ALTER KEYSPACE test WITH replication = {'class': 'SimpleStrategy', 'replication_factor': 3}" {
+		t.Fatal(stmts)
+	}
+}
@@ -0,0 +1,132 @@
+package gocassa
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+type counterT struct {
+	Table
+	keyFields []string
+}
+
+var counterType = reflect.TypeOf(Counter(0))
+
+// checkCounterFields checks that the row only holds its keys and counters, as Cassandra requires from tables having
+// counter columns
+func checkCounterFields(fields map[string]interface{}, keys Keys) error {
+	keyFields := append(append([]string{}, keys.PartitionKeys...), keys.ClusteringColumns...)
+	if len(keys.PartitionKeys) == 0 {
+		return fmt.Errorf("Counter tables need at least one partition key")
+	}
+	for _, key := range keyFields {
+		v, ok := fields[key]
+		if !ok {
+			return fmt.Errorf("Key %s is not a field of the row", key)
+		}
+		if reflect.TypeOf(v) == counterType {
+			return fmt.Errorf("Key %s can not be a counter", key)
+		}
+	}
+	counters := 0
+	for _, field := range sortedKeys(fields) {
+		if containsString(keyFields, field) {
+			continue
+		}
+		if reflect.TypeOf(fields[field]) != counterType {
+			return fmt.Errorf("Field %s of a counter table is neither a key nor a Counter", field)
+		}
+		counters++
+	}
+	if counters == 0 {
+		return fmt.Errorf("Counter tables need at least one Counter field")
+	}
+	return nil
+}
+
+// keyRelations returns the relations selecting the row having the given key, which must hold all the key fields
+func (c *counterT) keyRelations(key map[string]interface{}) ([]Relation, error) {
+	rels := make([]Relation, len(c.keyFields))
+	for i, field := range c.keyFields {
+		v, ok := key[field]
+		if !ok {
+			return nil, fmt.Errorf("Missing key field %s", field)
+		}
+		rels[i] = Eq(field, v)
+	}
+	if len(key) != len(c.keyFields) {
+		return nil, fmt.Errorf("Key %v holds fields which are not keys of the table", key)
+	}
+	return rels, nil
+}
+
+func (c *counterT) Increment(key map[string]interface{}, deltas map[string]int) Op {
+	rels, err := c.keyRelations(key)
+	if err != nil {
+		return &badOp{err}
+	}
+	if len(deltas) == 0 {
+		return Noop()
+	}
+	m := make(map[string]interface{}, len(deltas))
+	for field, delta := range deltas {
+		if containsString(c.keyFields, field) {
+			return &badOp{fmt.Errorf("Key field %s can not be incremented", field)}
+		}
+		m[field] = CounterIncrement(delta)
+	}
+	return c.Where(rels...).Update(m)
+}
+
+func (c *counterT) Read(key map[string]interface{}, pointer interface{}) Op {
+	rels, err := c.keyRelations(key)
+	if err != nil {
+		return &badOp{err}
+	}
+	return c.Where(rels...).ReadOne(pointer)
+}
+
+func (c *counterT) MultiRead(keys []map[string]interface{}, pointerToASlice interface{}) Op {
+	if len(keys) == 0 {
+		return &badOp{fmt.Errorf("No keys to read")}
+	}
+	values := make([][]interface{}, len(c.keyFields))
+	for _, key := range keys {
+		if _, err := c.keyRelations(key); err != nil {
+			return &badOp{err}
+		}
+		for i, field := range c.keyFields {
+			if !containsValue(values[i], key[field]) {
+				values[i] = append(values[i], key[field])
+			}
+		}
+	}
+	rels := make([]Relation, len(c.keyFields))
+	varying := []string{}
+	for i, field := range c.keyFields {
+		if len(values[i]) > 1 {
+			varying = append(varying, field)
+		}
+		rels[i] = In(field, values[i]...)
+	}
+	if len(varying) > 1 {
+		return &badOp{fmt.Errorf("The keys to read may only differ by one field, they differ by %s", strings.Join(varying, ", "))}
+	}
+	return c.Where(rels...).Read(pointerToASlice)
+}
+
+func (c *counterT) Reset(key map[string]interface{}) Op {
+	rels, err := c.keyRelations(key)
+	if err != nil {
+		return &badOp{err}
+	}
+	return c.Where(rels...).Delete()
+}
+
+func (c *counterT) WithOptions(o Options) CounterTable {
+	return &counterT{
+		Table:     c.Table.WithOptions(o),
+		keyFields: c.keyFields,
+	}
+}
@@ -0,0 +1,98 @@
+package gocassa
+
+import (
+	"reflect"
+	"testing"
+)
+
+type PageViews struct {
+	Page   string
+	Day    string
+	Views  Counter
+	Visits Counter
+}
+
+func TestCounterTable(t *testing.T) {
+	tbl, err := ns.CounterTable("pageviews", Keys{PartitionKeys: []string{"Page"}, ClusteringColumns: []string{"Day"}}, PageViews{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	createIf(tbl, t)
+	home := map[string]interface{}{"Page": "/", "Day": "2020-02-29"}
+	if err := tbl.Increment(home, map[string]int{"Views": 3, "Visits": 1}).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tbl.Increment(home, map[string]int{"Views": -1}).Run(); err != nil {
+		t.Fatal(err)
+	}
+	next := map[string]interface{}{"Page": "/", "Day": "2020-03-01"}
+	if err := tbl.Increment(next, map[string]int{"Visits": 5}).Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	var views PageViews
+	if err := tbl.Read(home, &views).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(views, PageViews{Page: "/", Day: "2020-02-29", Views: 2, Visits: 1}) {
+		t.Fatal(views)
+	}
+	var all []PageViews
+	if err := tbl.MultiRead([]map[string]interface{}{home, next}, &all).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || all[1].Views != 0 || all[1].Visits != 5 {
+		t.Fatal(all)
+	}
+
+	if err := tbl.Reset(home).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tbl.Read(home, &views).Run(); err == nil {
+		t.Fatal(views)
+	}
+}
+
+func TestCounterTableErrors(t *testing.T) {
+	ks := NewConnection(OptionCheckingQE{opts: &Options{}}).KeySpace("ks")
+	keys := Keys{PartitionKeys: []string{"Page"}, ClusteringColumns: []string{"Day"}}
+	for _, c := range []struct {
+		keys Keys
+		row  interface{}
+	}{
+		{Keys{}, PageViews{}},
+		{Keys{PartitionKeys: []string{"Path"}}, PageViews{}},
+		{Keys{PartitionKeys: []string{"Views"}}, PageViews{}},
+		{keys, struct {
+			Page, Day string
+		}{}},
+		{keys, struct {
+			Page, Day, Title string
+			Views            Counter
+		}{}},
+	} {
+		if _, err := ks.CounterTable("pageviews", c.keys, c.row); err == nil {
+			t.Errorf("Expected an error for %+v and %+v", c.keys, c.row)
+		}
+	}
+
+	tbl, err := ks.CounterTable("pageviews", keys, PageViews{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt, _ := tbl.Increment(map[string]interface{}{"Page": "/", "Day": "2020-02-29"}, map[string]int{"Views": 1}).GenerateStatement()
+	if stmt != "UPDATE ks.pageviews_counter_Page_Day SET Views = Views + ? WHERE page = ? AND day = ?" {
+		t.Fatal(stmt)
+	}
+	for _, op := range []Op{
+		tbl.Increment(map[string]interface{}{"Page": "/"}, map[string]int{"Views": 1}),
+		tbl.Increment(map[string]interface{}{"Page": "/", "Day": "2020-02-29"}, map[string]int{"Day": 1}),
+		tbl.Read(map[string]interface{}{"Page": "/", "Day": "2020-02-29", "Views": 1}, &PageViews{}),
+		tbl.MultiRead([]map[string]interface{}{{"Page": "/", "Day": "1"}, {"Page": "/about", "Day": "2"}}, &[]PageViews{}),
+		tbl.MultiRead(nil, &[]PageViews{}),
+	} {
+		if err := op.Run(); err == nil {
+			t.Errorf("Expected an error running %+v", op)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package gocassa
+
+import (
+	"context"
+)
+
+// deferredOp builds the op to run only when it is run, which lets recipes read the current state of rows before
+// deciding what to write, eg. to delete the index rows pointing to an old value. The writes it builds are run in a
+// logged batch if atomic is set, to keep the tables of the recipe consistent with each other. Only atomic ops, which
+// only read as they are built, can be part of other batches: their writes are then added to the batch.
+type deferredOp struct {
+	build   func(ctx context.Context, opts Options) (Op, error)
+	options Options
+	atomic  bool
+}
+
+func newDeferredOp(atomic bool, build func(ctx context.Context, opts Options) (Op, error)) Op {
+	return &deferredOp{build: build, atomic: atomic}
+}
+
+func (o *deferredOp) op(ctx context.Context) (Op, error) {
+	op, err := o.build(ctx, o.options)
+	if err != nil {
+		return nil, err
+	}
+	return op.WithOptions(o.options), nil
+}
+
+func (o *deferredOp) Run() error {
+	return o.RunContext(context.Background())
+}
+
+func (o *deferredOp) RunContext(ctx context.Context) error {
+	op, err := o.op(ctx)
+	if err != nil {
+		return err
+	}
+	if o.atomic {
+		return op.RunAtomicallyContext(ctx)
+	}
+	return op.RunContext(ctx)
+}
+
+func (o *deferredOp) RunAtomically() error {
+	return o.RunAtomicallyContext(context.Background())
+}
+
+func (o *deferredOp) RunAtomicallyContext(ctx context.Context) error {
+	return o.RunBatchContext(ctx, LoggedBatch)
+}
+
+func (o *deferredOp) RunBatch(batchType BatchType) error {
+	return o.RunBatchContext(context.Background(), batchType)
+}
+
+func (o *deferredOp) RunBatchContext(ctx context.Context, batchType BatchType) error {
+	return multiOp{o}.RunBatchContext(ctx, batchType)
+}
+
+func (o *deferredOp) RunParallel(concurrency int) error {
+	return o.Run()
+}
+
+func (o *deferredOp) RunParallelContext(ctx context.Context, concurrency int) error {
+	return o.RunContext(ctx)
+}
+
+func (o *deferredOp) Add(additions ...Op) Op {
+	return multiOp{o}.Add(additions...)
+}
+
+func (o *deferredOp) WithOptions(opts Options) Op {
+	return &deferredOp{
+		build:   o.build,
+		options: o.options.Merge(opts),
+		atomic:  o.atomic,
+	}
+}
+
+func (o *deferredOp) Preflight() error {
+	return nil
+}
+
+// GenerateStatement returns no statement, as the statements are only known once the op is run
+func (o *deferredOp) GenerateStatement() (string, []interface{}) {
+	return "", []interface{}{}
+}
+
+func (o *deferredOp) QueryExecutor() QueryExecutor {
+	return nil
+}
@@ -19,3 +19,14 @@ func (r RowNotFoundError) Error() string {
 	}
 	return fmt.Sprintf("%v:%v: No rows returned", f, r.line)
 }
+
+// MultiError is returned by RunParallel, it holds the errors of all the operations which failed.
+type MultiError []error
+
+func (m MultiError) Error() string {
+	msgs := make([]string, len(m))
+	for i, err := range m {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d errors occurred: %s", len(m), strings.Join(msgs, "; "))
+}
@@ -1,16 +1,32 @@
 package gocassa
 
+import (
+	"context"
+)
+
 type filter struct {
 	t  t
 	rs []Relation
 }
 
 func (f filter) Update(m map[string]interface{}) Op {
-	return newWriteOp(f.t.keySpace.qe, f, updateOpType, m)
+	return newWriteOp(f.t.keySpace.queryExecutor(), f, updateOpType, m)
 }
 
 func (f filter) Delete() Op {
-	return newWriteOp(f.t.keySpace.qe, f, deleteOpType, nil)
+	return newWriteOp(f.t.keySpace.queryExecutor(), f, deleteOpType, nil)
+}
+
+func (f filter) UpdateIf(conditions []Relation, m map[string]interface{}, result *CASResult) Op {
+	op := newWriteOp(f.t.keySpace.queryExecutor(), f, updateOpType, m)
+	op.cas = &casCondition{conditions: conditions, result: result}
+	return op
+}
+
+func (f filter) DeleteIf(conditions []Relation, result *CASResult) Op {
+	op := newWriteOp(f.t.keySpace.queryExecutor(), f, deleteOpType, nil)
+	op.cas = &casCondition{conditions: conditions, result: result}
+	return op
 }
 
 //
@@ -19,7 +35,7 @@ func (f filter) Delete() Op {
 
 func (f filter) Read(pointerToASlice interface{}) Op {
 	return &singleOp{
-		qe:     f.t.keySpace.qe,
+		qe:     f.t.keySpace.queryExecutor(),
 		f:      f,
 		opType: readOpType,
 		result: pointerToASlice}
@@ -27,8 +43,20 @@ func (f filter) Read(pointerToASlice interface{}) Op {
 
 func (f filter) ReadOne(pointer interface{}) Op {
 	return &singleOp{
-		qe:     f.t.keySpace.qe,
+		qe:     f.t.keySpace.queryExecutor(),
 		f:      f,
 		opType: singleReadOpType,
 		result: pointer}
 }
+
+func (f filter) Iter() Iterator {
+	return f.IterContext(context.Background())
+}
+
+func (f filter) IterContext(ctx context.Context) Iterator {
+	op := &singleOp{
+		qe:     f.t.keySpace.queryExecutor(),
+		f:      f,
+		opType: readOpType}
+	return op.iter(ctx)
+}
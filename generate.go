@@ -3,11 +3,14 @@ package gocassa
 import (
 	"errors"
 	"fmt"
+	"math/big"
+	"net"
 	"reflect"
 	"strings"
 	"time"
 
 	"github.com/gocql/gocql"
+	"gopkg.in/inf.v0"
 )
 
 // CREATE TABLE users (
@@ -28,23 +31,22 @@ import (
 // );
 //
 
-func createTableIfNotExist(keySpace, cf string, partitionKeys, colKeys []string, fields []string, values []interface{}, order []ClusteringOrderColumn, compoundKey, compact bool, compressor string) (string, error) {
-	return createTableStmt("CREATE TABLE IF NOT EXISTS", keySpace, cf, partitionKeys, colKeys, fields, values, order, compoundKey, compact, compressor)
+func createTableIfNotExist(keySpace, cf string, partitionKeys, colKeys, statics, fields, types []string, order []ClusteringOrderColumn, compoundKey, compact bool, props TableProperties) (string, error) {
+	return createTableStmt("CREATE TABLE IF NOT EXISTS", keySpace, cf, partitionKeys, colKeys, statics, fields, types, order, compoundKey, compact, props)
 }
 
-func createTable(keySpace, cf string, partitionKeys, colKeys []string, fields []string, values []interface{}, order []ClusteringOrderColumn, compoundKey, compact bool, compressor string) (string, error) {
-	return createTableStmt("CREATE TABLE", keySpace, cf, partitionKeys, colKeys, fields, values, order, compoundKey, compact, compressor)
+func createTable(keySpace, cf string, partitionKeys, colKeys, statics, fields, types []string, order []ClusteringOrderColumn, compoundKey, compact bool, props TableProperties) (string, error) {
+	return createTableStmt("CREATE TABLE", keySpace, cf, partitionKeys, colKeys, statics, fields, types, order, compoundKey, compact, props)
 }
 
-func createTableStmt(createStmt, keySpace, cf string, partitionKeys, colKeys []string, fields []string, values []interface{}, order []ClusteringOrderColumn, compoundKey, compact bool, compressor string) (string, error) {
+func createTableStmt(createStmt, keySpace, cf string, partitionKeys, colKeys, statics, fields, types []string, order []ClusteringOrderColumn, compoundKey, compact bool, props TableProperties) (string, error) {
 	firstLine := fmt.Sprintf("%s %v.%v (", createStmt, keySpace, cf)
 	fieldLines := []string{}
 	for i, _ := range fields {
-		typeStr, err := stringTypeOf(values[i])
-		if err != nil {
-			return "", err
+		l := "    " + strings.ToLower(fields[i]) + " " + types[i]
+		if containsString(statics, fields[i]) {
+			l += " STATIC"
 		}
-		l := "    " + strings.ToLower(fields[i]) + " " + typeStr
 		fieldLines = append(fieldLines, l)
 	}
 	//key generation
@@ -65,31 +67,24 @@ func createTableStmt(createStmt, keySpace, cf string, partitionKeys, colKeys []s
 		")",
 	}
 
+	with := []string{}
 	if len(order) > 0 {
 		orderStrs := make([]string, len(order))
 		for i, o := range order {
 			orderStrs[i] = fmt.Sprintf("%v %v", o.Column, o.Direction.String())
 		}
-		orderLine := fmt.Sprintf("WITH CLUSTERING ORDER BY (%v)", strings.Join(orderStrs, ", "))
-		lines = append(lines, orderLine)
+		with = append(with, fmt.Sprintf("CLUSTERING ORDER BY (%v)", strings.Join(orderStrs, ", ")))
 	}
-
 	if compact {
-		compactLineStart := "WITH"
-		if len(order) > 0 {
-			compactLineStart = "AND"
-		}
-		compactLine := fmt.Sprintf("%v COMPACT STORAGE", compactLineStart)
-		lines = append(lines, compactLine)
+		with = append(with, "COMPACT STORAGE")
 	}
-
-	if len(compressor) > 0 {
-		compressionLineStart := "WITH"
-		if len(order) > 0 || compact {
-			compressionLineStart = "AND"
+	with = append(with, props.cql()...)
+	for i, w := range with {
+		if i == 0 {
+			lines = append(lines, "WITH "+w)
+		} else {
+			lines = append(lines, "AND "+w)
 		}
-		compressionLine := fmt.Sprintf("%v compression = {'sstable_compression': '%v'}", compressionLineStart, compressor)
-		lines = append(lines, compressionLine)
 	}
 
 	lines = append(lines, ";")
@@ -105,8 +100,16 @@ func j(s []string) string {
 	return strings.Join(s1, ", ")
 }
 
-func createKeyspace(keyspaceName string) string {
-	return fmt.Sprintf("CREATE KEYSPACE \"%v\" WITH REPLICATION = {'class' : 'NetworkTopologyStrategy', 'eu-west-1' : 3}", keyspaceName)
+func keySpaceStmt(verb, name string, opts KeySpaceOptions) (string, error) {
+	replication, err := opts.replication()
+	if err != nil {
+		return "", err
+	}
+	stmt := fmt.Sprintf("%s %s WITH replication = %s", verb, name, replication)
+	if opts.DurableWrites != nil {
+		stmt += fmt.Sprintf(" AND durable_writes = %t", *opts.DurableWrites)
+	}
+	return stmt, nil
 }
 
 func cassaType(i interface{}) gocql.Type {
@@ -115,8 +118,10 @@ func cassaType(i interface{}) gocql.Type {
 		return gocql.TypeInt
 	case int64:
 		return gocql.TypeBigInt
-	case int8, int16, uint, uint8, uint16, uint32, uint64:
+	case int8, int16, uint, uint8, uint16, uint32, uint64, *big.Int:
 		return gocql.TypeVarint
+	case *inf.Dec:
+		return gocql.TypeDecimal
 	case string:
 		return gocql.TypeVarchar
 	case float32:
@@ -127,6 +132,10 @@ func cassaType(i interface{}) gocql.Type {
 		return gocql.TypeBoolean
 	case time.Time:
 		return gocql.TypeTimestamp
+	case gocql.Duration:
+		return gocql.TypeDuration
+	case net.IP:
+		return gocql.TypeInet
 	case gocql.UUID:
 		return gocql.TypeUUID
 	case []byte:
@@ -157,25 +166,27 @@ func cassaType(i interface{}) gocql.Type {
 
 func stringTypeOf(i interface{}) (string, error) {
 	_, isByteSlice := i.([]byte)
-	if !isByteSlice {
+	_, isIP := i.(net.IP)
+	if !isByteSlice && !isIP {
 		// Check if we found a higher kinded type
-		switch reflect.ValueOf(i).Kind() {
+		switch typ := reflect.TypeOf(i); reflect.ValueOf(i).Kind() {
 		case reflect.Slice:
-			elemVal := reflect.Indirect(reflect.New(reflect.TypeOf(i).Elem())).Interface()
-			ct := cassaType(elemVal)
-			if ct == gocql.TypeCustom {
+			elemType, err := elemTypeOf(typ.Elem())
+			if err != nil {
 				return "", fmt.Errorf("Unsupported type %T", i)
 			}
-			return fmt.Sprintf("list<%v>", ct), nil
+			return fmt.Sprintf("list<%v>", elemType), nil
 		case reflect.Map:
-			keyVal := reflect.Indirect(reflect.New(reflect.TypeOf(i).Key())).Interface()
-			elemVal := reflect.Indirect(reflect.New(reflect.TypeOf(i).Elem())).Interface()
-			keyCt := cassaType(keyVal)
-			elemCt := cassaType(elemVal)
-			if keyCt == gocql.TypeCustom || elemCt == gocql.TypeCustom {
+			keyType, keyErr := elemTypeOf(typ.Key())
+			elemType, elemErr := elemTypeOf(typ.Elem())
+			if keyErr != nil || elemErr != nil {
 				return "", fmt.Errorf("Unsupported map key or value type %T", i)
 			}
-			return fmt.Sprintf("map<%v, %v>", keyCt, elemCt), nil
+			return fmt.Sprintf("map<%v, %v>", keyType, elemType), nil
+		case reflect.Struct, reflect.Ptr:
+			if isUDT(typ) {
+				return udtStringType(typ)
+			}
 		}
 	}
 	ct := cassaType(i)
@@ -185,12 +196,35 @@ func stringTypeOf(i interface{}) (string, error) {
 	return cassaTypeToString(ct)
 }
 
+// elemTypeOf returns the type of the keys or elements of collections and tuples, where collections are frozen
+func elemTypeOf(t reflect.Type) (string, error) {
+	if isUDT(t) {
+		return udtStringType(t)
+	}
+	if (t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8) || t.Kind() == reflect.Map {
+		typ, err := stringTypeOf(reflect.Zero(t).Interface())
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("frozen<%s>", typ), nil
+	}
+	ct := cassaType(reflect.Zero(t).Interface())
+	if ct == gocql.TypeCustom {
+		return "", fmt.Errorf("Unsupported type %v", t)
+	}
+	return cassaTypeToString(ct)
+}
+
 func cassaTypeToString(t gocql.Type) (string, error) {
 	switch t {
 	case gocql.TypeInt:
 		return "int", nil
 	case gocql.TypeBigInt:
 		return "bigint", nil
+	case gocql.TypeSmallInt:
+		return "smallint", nil
+	case gocql.TypeTinyInt:
+		return "tinyint", nil
 	case gocql.TypeVarint:
 		return "varint", nil
 	case gocql.TypeVarchar:
@@ -199,12 +233,24 @@ func cassaTypeToString(t gocql.Type) (string, error) {
 		return "float", nil
 	case gocql.TypeDouble:
 		return "double", nil
+	case gocql.TypeDecimal:
+		return "decimal", nil
 	case gocql.TypeBoolean:
 		return "boolean", nil
 	case gocql.TypeTimestamp:
 		return "timestamp", nil
+	case gocql.TypeDate:
+		return "date", nil
+	case gocql.TypeTime:
+		return "time", nil
+	case gocql.TypeDuration:
+		return "duration", nil
 	case gocql.TypeUUID:
 		return "uuid", nil
+	case gocql.TypeTimeUUID:
+		return "timeuuid", nil
+	case gocql.TypeInet:
+		return "inet", nil
 	case gocql.TypeBlob:
 		return "blob", nil
 	case gocql.TypeCounter:
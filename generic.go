@@ -0,0 +1,225 @@
+//go:build go1.18
+// +build go1.18
+
+package gocassa
+
+import (
+	"context"
+	"time"
+)
+
+// The tables below are type safe versions of the recipe tables: rows are of type V and results are returned
+// instead of being decoded into a pointer passed as an interface{}. V must be a struct type. They are thin wrappers
+// around the recipe tables, which remain accessible through Untyped for anything not covered here, like batching
+// several ops together.
+
+// TypedMapTable is the type safe version of MapTable, with keys of type K and rows of type V
+type TypedMapTable[K comparable, V any] struct {
+	TableChanger
+	table MapTable
+}
+
+// NewMapTable returns a type safe MapTable, see KeySpace.MapTable
+func NewMapTable[K comparable, V any](ks KeySpace, name, idField string) *TypedMapTable[K, V] {
+	var row V
+	t := ks.MapTable(name, idField, row)
+	return &TypedMapTable[K, V]{
+		TableChanger: t,
+		table:        t,
+	}
+}
+
+func (m *TypedMapTable[K, V]) Set(v V) Op {
+	return m.table.Set(v)
+}
+
+func (m *TypedMapTable[K, V]) Update(id K, fields map[string]interface{}) Op {
+	return m.table.Update(id, fields)
+}
+
+func (m *TypedMapTable[K, V]) Delete(id K) Op {
+	return m.table.Delete(id)
+}
+
+func (m *TypedMapTable[K, V]) Read(id K) (V, error) {
+	return m.ReadContext(context.Background(), id)
+}
+
+func (m *TypedMapTable[K, V]) ReadContext(ctx context.Context, id K) (V, error) {
+	var v V
+	err := m.table.Read(id, &v).RunContext(ctx)
+	return v, err
+}
+
+func (m *TypedMapTable[K, V]) MultiRead(ids ...K) ([]V, error) {
+	return m.MultiReadContext(context.Background(), ids...)
+}
+
+func (m *TypedMapTable[K, V]) MultiReadContext(ctx context.Context, ids ...K) ([]V, error) {
+	vs := []V{}
+	err := m.table.MultiRead(toInterfaces(ids), &vs).RunContext(ctx)
+	return vs, err
+}
+
+func (m *TypedMapTable[K, V]) WithOptions(o Options) *TypedMapTable[K, V] {
+	t := m.table.WithOptions(o)
+	return &TypedMapTable[K, V]{
+		TableChanger: t,
+		table:        t,
+	}
+}
+
+// Untyped returns the underlying MapTable
+func (m *TypedMapTable[K, V]) Untyped() MapTable {
+	return m.table
+}
+
+// TypedMultimapTable is the type safe version of MultimapTable, with rows of type V indexed by a field of type P and
+// identified by a field of type K
+type TypedMultimapTable[P, K comparable, V any] struct {
+	TableChanger
+	table MultimapTable
+}
+
+// NewMultimapTable returns a type safe MultimapTable, see KeySpace.MultimapTable
+func NewMultimapTable[P, K comparable, V any](ks KeySpace, name, fieldToIndexBy, idField string) *TypedMultimapTable[P, K, V] {
+	var row V
+	t := ks.MultimapTable(name, fieldToIndexBy, idField, row)
+	return &TypedMultimapTable[P, K, V]{
+		TableChanger: t,
+		table:        t,
+	}
+}
+
+func (mm *TypedMultimapTable[P, K, V]) Set(v V) Op {
+	return mm.table.Set(v)
+}
+
+func (mm *TypedMultimapTable[P, K, V]) Update(field P, id K, fields map[string]interface{}) Op {
+	return mm.table.Update(field, id, fields)
+}
+
+func (mm *TypedMultimapTable[P, K, V]) Delete(field P, id K) Op {
+	return mm.table.Delete(field, id)
+}
+
+func (mm *TypedMultimapTable[P, K, V]) DeleteAll(field P) Op {
+	return mm.table.DeleteAll(field)
+}
+
+func (mm *TypedMultimapTable[P, K, V]) Read(field P, id K) (V, error) {
+	return mm.ReadContext(context.Background(), field, id)
+}
+
+func (mm *TypedMultimapTable[P, K, V]) ReadContext(ctx context.Context, field P, id K) (V, error) {
+	var v V
+	err := mm.table.Read(field, id, &v).RunContext(ctx)
+	return v, err
+}
+
+func (mm *TypedMultimapTable[P, K, V]) MultiRead(field P, ids ...K) ([]V, error) {
+	return mm.MultiReadContext(context.Background(), field, ids...)
+}
+
+func (mm *TypedMultimapTable[P, K, V]) MultiReadContext(ctx context.Context, field P, ids ...K) ([]V, error) {
+	vs := []V{}
+	err := mm.table.MultiRead(field, toInterfaces(ids), &vs).RunContext(ctx)
+	return vs, err
+}
+
+// List returns at most limit rows, starting from startId if it is not nil
+func (mm *TypedMultimapTable[P, K, V]) List(field P, startId *K, limit int) ([]V, error) {
+	return mm.ListContext(context.Background(), field, startId, limit)
+}
+
+func (mm *TypedMultimapTable[P, K, V]) ListContext(ctx context.Context, field P, startId *K, limit int) ([]V, error) {
+	var start interface{}
+	if startId != nil {
+		start = *startId
+	}
+	vs := []V{}
+	err := mm.table.List(field, start, limit, &vs).RunContext(ctx)
+	return vs, err
+}
+
+func (mm *TypedMultimapTable[P, K, V]) WithOptions(o Options) *TypedMultimapTable[P, K, V] {
+	t := mm.table.WithOptions(o)
+	return &TypedMultimapTable[P, K, V]{
+		TableChanger: t,
+		table:        t,
+	}
+}
+
+// Untyped returns the underlying MultimapTable
+func (mm *TypedMultimapTable[P, K, V]) Untyped() MultimapTable {
+	return mm.table
+}
+
+// TypedTimeSeriesTable is the type safe version of TimeSeriesTable, with ids of type K and rows of type V
+type TypedTimeSeriesTable[K comparable, V any] struct {
+	TableChanger
+	table TimeSeriesTable
+}
+
+// NewTimeSeriesTable returns a type safe TimeSeriesTable, see KeySpace.TimeSeriesTable
+func NewTimeSeriesTable[K comparable, V any](ks KeySpace, name, timeField, idField string, bucketSize time.Duration) *TypedTimeSeriesTable[K, V] {
+	var row V
+	t := ks.TimeSeriesTable(name, timeField, idField, bucketSize, row)
+	return &TypedTimeSeriesTable[K, V]{
+		TableChanger: t,
+		table:        t,
+	}
+}
+
+func (o *TypedTimeSeriesTable[K, V]) Set(v V) Op {
+	return o.table.Set(v)
+}
+
+func (o *TypedTimeSeriesTable[K, V]) Update(timeStamp time.Time, id K, fields map[string]interface{}) Op {
+	return o.table.Update(timeStamp, id, fields)
+}
+
+func (o *TypedTimeSeriesTable[K, V]) Delete(timeStamp time.Time, id K) Op {
+	return o.table.Delete(timeStamp, id)
+}
+
+func (o *TypedTimeSeriesTable[K, V]) Read(timeStamp time.Time, id K) (V, error) {
+	return o.ReadContext(context.Background(), timeStamp, id)
+}
+
+func (o *TypedTimeSeriesTable[K, V]) ReadContext(ctx context.Context, timeStamp time.Time, id K) (V, error) {
+	var v V
+	err := o.table.Read(timeStamp, id, &v).RunContext(ctx)
+	return v, err
+}
+
+func (o *TypedTimeSeriesTable[K, V]) List(start, end time.Time) ([]V, error) {
+	return o.ListContext(context.Background(), start, end)
+}
+
+func (o *TypedTimeSeriesTable[K, V]) ListContext(ctx context.Context, start, end time.Time) ([]V, error) {
+	vs := []V{}
+	err := o.table.List(start, end, &vs).RunContext(ctx)
+	return vs, err
+}
+
+func (o *TypedTimeSeriesTable[K, V]) WithOptions(opt Options) *TypedTimeSeriesTable[K, V] {
+	t := o.table.WithOptions(opt)
+	return &TypedTimeSeriesTable[K, V]{
+		TableChanger: t,
+		table:        t,
+	}
+}
+
+// Untyped returns the underlying TimeSeriesTable
+func (o *TypedTimeSeriesTable[K, V]) Untyped() TimeSeriesTable {
+	return o.table
+}
+
+func toInterfaces[T any](s []T) []interface{} {
+	ret := make([]interface{}, len(s))
+	for i, v := range s {
+		ret[i] = v
+	}
+	return ret
+}
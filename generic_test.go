@@ -0,0 +1,93 @@
+//go:build go1.18
+// +build go1.18
+
+package gocassa
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTypedMapTable(t *testing.T) {
+	tbl := NewMapTable[int, user](NewMockKeySpace(), "users", "Pk1")
+	for _, u := range []user{{Pk1: 1, Name: "John"}, {Pk1: 2, Name: "Jane"}} {
+		if err := tbl.Set(u).Run(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	u, err := tbl.Read(1)
+	if err != nil || u.Name != "John" {
+		t.Fatal(u, err)
+	}
+	if _, err := tbl.Read(3); err == nil {
+		t.Fatal("Expected an error reading a missing row")
+	}
+
+	if err := tbl.Update(2, map[string]interface{}{"Name": "Joan"}).Run(); err != nil {
+		t.Fatal(err)
+	}
+	users, err := tbl.MultiRead(1, 2, 3)
+	if err != nil || len(users) != 2 || users[1].Name != "Joan" {
+		t.Fatal(users, err)
+	}
+
+	if err := tbl.Delete(1).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if users, err := tbl.WithOptions(Options{Limit: 1}).MultiRead(1, 2); err != nil || len(users) != 1 || users[0].Pk1 != 2 {
+		t.Fatal(users, err)
+	}
+}
+
+func TestTypedMultimapTable(t *testing.T) {
+	tbl := NewMultimapTable[int, int, user](NewMockKeySpace(), "users", "Pk1", "Pk2")
+	for i := 0; i < 5; i++ {
+		if err := tbl.Set(user{Pk1: 1, Pk2: i, Name: "John"}).Run(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	u, err := tbl.Read(1, 3)
+	if err != nil || u.Pk2 != 3 {
+		t.Fatal(u, err)
+	}
+
+	users, err := tbl.List(1, nil, 2)
+	if err != nil || len(users) != 2 || users[0].Pk2 != 0 {
+		t.Fatal(users, err)
+	}
+	start := 3
+	users, err = tbl.List(1, &start, 10)
+	if err != nil || len(users) != 2 || users[0].Pk2 != 3 {
+		t.Fatal(users, err)
+	}
+
+	if err := tbl.DeleteAll(1).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if users, err := tbl.MultiRead(1, 0, 1); err != nil || len(users) != 0 {
+		t.Fatal(users, err)
+	}
+}
+
+func TestTypedTimeSeriesTable(t *testing.T) {
+	tbl := NewTimeSeriesTable[int, point](NewMockKeySpace(), "points", "Time", "Id", time.Minute)
+	now := time.Now().Truncate(time.Minute)
+	for i := 0; i < 3; i++ {
+		p := point{Time: now.Add(time.Duration(i) * time.Minute), Id: i, X: float64(i)}
+		if err := tbl.Set(p).Run(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p, err := tbl.Read(now.Add(time.Minute), 1)
+	if err != nil || p.X != 1 {
+		t.Fatal(p, err)
+	}
+
+	points, err := tbl.List(now, now.Add(90*time.Second))
+	if err != nil || len(points) != 2 {
+		t.Fatal(points, err)
+	}
+}
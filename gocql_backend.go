@@ -1,6 +1,7 @@
 package gocassa
 
 import (
+	"context"
 	"errors"
 
 	"github.com/gocql/gocql"
@@ -11,11 +12,11 @@ type goCQLBackend struct {
 }
 
 func (cb goCQLBackend) QueryWithOptions(opts Options, stmt string, vals ...interface{}) ([]map[string]interface{}, error) {
-	qu := cb.session.Query(stmt, vals...)
-	if opts.Consistency != nil {
-		qu = qu.Consistency(*opts.Consistency)
-	}
-	iter := qu.Iter()
+	return cb.QueryContext(context.Background(), opts, stmt, vals...)
+}
+
+func (cb goCQLBackend) QueryContext(ctx context.Context, opts Options, stmt string, vals ...interface{}) ([]map[string]interface{}, error) {
+	iter := cb.QueryIter(ctx, opts, stmt, vals...)
 	ret := []map[string]interface{}{}
 	m := &map[string]interface{}{}
 	for iter.MapScan(*m) {
@@ -25,23 +26,58 @@ func (cb goCQLBackend) QueryWithOptions(opts Options, stmt string, vals ...inter
 	return ret, iter.Close()
 }
 
+func (cb goCQLBackend) QueryIter(ctx context.Context, opts Options, stmt string, vals ...interface{}) RowIterator {
+	qu := cb.session.Query(stmt, vals...).WithContext(ctx)
+	if opts.Consistency != nil {
+		qu = qu.Consistency(*opts.Consistency)
+	}
+	if opts.Paging != nil {
+		qu = qu.PageSize(opts.Paging.PageSize).PageState(opts.Paging.State)
+	}
+	iter := qu.Iter()
+	if opts.Paging != nil {
+		opts.Paging.State = iter.PageState()
+	}
+	return iter
+}
+
 func (cb goCQLBackend) Query(stmt string, vals ...interface{}) ([]map[string]interface{}, error) {
 	return cb.QueryWithOptions(Options{}, stmt, vals...)
 }
 
 func (cb goCQLBackend) ExecuteWithOptions(opts Options, stmt string, vals ...interface{}) error {
-	qu := cb.session.Query(stmt, vals...)
+	return cb.ExecuteContext(context.Background(), opts, stmt, vals...)
+}
+
+func (cb goCQLBackend) ExecuteContext(ctx context.Context, opts Options, stmt string, vals ...interface{}) error {
+	qu := cb.session.Query(stmt, vals...).WithContext(ctx)
 	if opts.Consistency != nil {
 		qu = qu.Consistency(*opts.Consistency)
 	}
 	return qu.Exec()
 }
 
+func (cb goCQLBackend) ExecuteCAS(ctx context.Context, opts Options, current map[string]interface{}, stmt string, vals ...interface{}) (bool, error) {
+	qu := cb.session.Query(stmt, vals...).WithContext(ctx)
+	if opts.Consistency != nil {
+		qu = qu.Consistency(*opts.Consistency)
+	}
+	return qu.MapScanCAS(current)
+}
+
 func (cb goCQLBackend) Execute(stmt string, vals ...interface{}) error {
 	return cb.ExecuteWithOptions(Options{}, stmt, vals...)
 }
 
 func (cb goCQLBackend) ExecuteAtomically(stmts []string, vals [][]interface{}) error {
+	return cb.ExecuteAtomicallyContext(context.Background(), stmts, vals)
+}
+
+func (cb goCQLBackend) ExecuteAtomicallyContext(ctx context.Context, stmts []string, vals [][]interface{}) error {
+	return cb.ExecuteBatch(ctx, LoggedBatch, stmts, vals)
+}
+
+func (cb goCQLBackend) ExecuteBatch(ctx context.Context, batchType BatchType, stmts []string, vals [][]interface{}) error {
 	if len(stmts) != len(vals) {
 		return errors.New("executeBatched: stmts length != param length")
 	}
@@ -49,7 +85,7 @@ func (cb goCQLBackend) ExecuteAtomically(stmts []string, vals [][]interface{}) e
 	if len(stmts) == 0 {
 		return nil
 	}
-	batch := cb.session.NewBatch(gocql.LoggedBatch)
+	batch := cb.session.NewBatch(gocql.BatchType(batchType)).WithContext(ctx)
 	for i, _ := range stmts {
 		batch.Query(stmts[i], vals[i]...)
 	}
@@ -82,3 +118,31 @@ func newGoCQLBackend(nodeIps []string, username, password string) (QueryExecutor
 	}
 	return GoCQLSessionToQueryExecutor(sess), nil
 }
+
+// gocqlTypeInfo describes the column type values are marshalled to when gocassa compares them itself
+type gocqlTypeInfo struct {
+	proto byte
+	typ   gocql.Type
+}
+
+func (t gocqlTypeInfo) New() interface{} {
+	return &gocqlTypeInfo{t.proto, t.typ}
+}
+
+func (t gocqlTypeInfo) Type() gocql.Type {
+	return t.typ
+}
+
+func (t gocqlTypeInfo) Version() byte {
+	return t.proto
+}
+
+func (t gocqlTypeInfo) Custom() string {
+	return ""
+}
+
+// marshalValue marshals the value into the bytes Cassandra stores it as in a column of the given type. The encoding
+// of values is the same with every version of the native protocol from 3 onwards.
+func marshalValue(typ gocql.Type, v interface{}) ([]byte, error) {
+	return gocql.Marshal(gocqlTypeInfo{proto: 0x03, typ: typ}, v)
+}
@@ -0,0 +1,198 @@
+package gocassa
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+)
+
+type indexedEntityT struct {
+	tableGroup
+	primary     MapTable
+	indexes     []MultimapTable
+	idField     string
+	indexFields []string
+	rowType     reflect.Type
+}
+
+func newIndexedEntityT(name, idField string, indexFields []string, rowType reflect.Type, primary MapTable, indexes []MultimapTable) *indexedEntityT {
+	tables := []TableChanger{primary}
+	for _, index := range indexes {
+		tables = append(tables, index)
+	}
+	return &indexedEntityT{
+		tableGroup:  tableGroup{name: name, tables: tables},
+		primary:     primary,
+		indexes:     indexes,
+		idField:     idField,
+		indexFields: indexFields,
+		rowType:     rowType,
+	}
+}
+
+// current reads the entity having the id as a map, it returns false if there is none
+func (e *indexedEntityT) current(ctx context.Context, opts Options, id interface{}) (map[string]interface{}, bool, error) {
+	row := reflect.New(e.rowType)
+	err := e.primary.Read(id, row.Interface()).WithOptions(opts).RunContext(ctx)
+	if _, ok := err.(RowNotFoundError); ok {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	m, ok := toMap(row.Elem().Interface())
+	if !ok {
+		return nil, false, fmt.Errorf("Can't convert %s to a map", e.rowType)
+	}
+	return m, true, nil
+}
+
+// sameKeyValue tells whether the values are the same once written to Cassandra, eg. times which only differ by
+// nanoseconds are the same timestamp. Deleting an index row and setting it again in the same batch would delete it.
+func sameKeyValue(a, b interface{}) bool {
+	// Entities stored as maps may lack the field
+	if a == nil || b == nil {
+		return a == b
+	}
+	typ := cassaType(a)
+	aBytes, aErr := marshalValue(typ, a)
+	bBytes, bErr := marshalValue(typ, b)
+	if aErr != nil || bErr != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	return bytes.Equal(aBytes, bBytes)
+}
+
+// staleIndexes returns the delete ops of the index rows which point to the old values of the indexed fields
+func (e *indexedEntityT) staleIndexes(old, neu map[string]interface{}) Op {
+	op := Noop()
+	for i, field := range e.indexFields {
+		if v, ok := neu[field]; ok && !sameKeyValue(old[field], v) {
+			op = op.Add(e.indexes[i].Delete(old[field], old[e.idField]))
+		}
+	}
+	return op
+}
+
+func (e *indexedEntityT) Set(v interface{}) Op {
+	m, ok := toMap(v)
+	if !ok {
+		return &badOp{fmt.Errorf("Can't convert %T to a map", v)}
+	}
+	id, ok := m[e.idField]
+	if !ok {
+		return &badOp{fmt.Errorf("Id field %s is missing", e.idField)}
+	}
+	return newDeferredOp(true, func(ctx context.Context, opts Options) (Op, error) {
+		old, ok, err := e.current(ctx, opts, id)
+		if err != nil {
+			return nil, err
+		}
+		op := Noop()
+		if ok {
+			op = e.staleIndexes(old, m)
+		}
+		op = op.Add(e.primary.Set(v))
+		for _, index := range e.indexes {
+			op = op.Add(index.Set(v))
+		}
+		return op, nil
+	})
+}
+
+func (e *indexedEntityT) Update(id interface{}, m map[string]interface{}) Op {
+	if _, ok := m[e.idField]; ok {
+		return &badOp{fmt.Errorf("Id field %s can not be updated", e.idField)}
+	}
+	for _, field := range e.indexFields {
+		if _, ok := m[field].(Modifier); ok {
+			return &badOp{fmt.Errorf("Indexed field %s can not be updated with a modifier", field)}
+		}
+	}
+	return newDeferredOp(true, func(ctx context.Context, opts Options) (Op, error) {
+		old, ok, err := e.current(ctx, opts, id)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, RowNotFoundError{}
+		}
+		// The rows of the indexes whose field changes are moved, so they are written whole
+		neu := make(map[string]interface{}, len(old))
+		for field, v := range old {
+			neu[field] = v
+		}
+		for field, v := range m {
+			if _, ok := v.(Modifier); ok {
+				continue
+			}
+			neu[field] = v
+		}
+		op := e.staleIndexes(old, m).Add(e.primary.Update(id, m))
+		for i, field := range e.indexFields {
+			if v, ok := m[field]; !ok || sameKeyValue(old[field], v) {
+				// The key fields of the index can not be set by an update
+				fields := make(map[string]interface{}, len(m))
+				for f, v := range m {
+					if f != field {
+						fields[f] = v
+					}
+				}
+				if len(fields) > 0 {
+					op = op.Add(e.indexes[i].Update(old[field], id, fields))
+				}
+				continue
+			}
+			for f, v := range m {
+				if _, ok := v.(Modifier); ok {
+					return nil, fmt.Errorf("Field %s can not be updated with a modifier while indexed field %s changes", f, field)
+				}
+			}
+			op = op.Add(e.indexes[i].Set(neu))
+		}
+		return op, nil
+	})
+}
+
+func (e *indexedEntityT) Delete(id interface{}) Op {
+	return newDeferredOp(true, func(ctx context.Context, opts Options) (Op, error) {
+		old, ok, err := e.current(ctx, opts, id)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return Noop(), nil
+		}
+		op := e.primary.Delete(id)
+		for i, field := range e.indexFields {
+			op = op.Add(e.indexes[i].Delete(old[field], id))
+		}
+		return op, nil
+	})
+}
+
+func (e *indexedEntityT) Read(id, pointer interface{}) Op {
+	return e.primary.Read(id, pointer)
+}
+
+func (e *indexedEntityT) MultiRead(ids []interface{}, pointerToASlice interface{}) Op {
+	return e.primary.MultiRead(ids, pointerToASlice)
+}
+
+func (e *indexedEntityT) List(field string, v, startId interface{}, limit int, pointerToASlice interface{}) Op {
+	for i, indexField := range e.indexFields {
+		if indexField == field {
+			return e.indexes[i].List(v, startId, limit, pointerToASlice)
+		}
+	}
+	return &badOp{fmt.Errorf("Field %s is not indexed", field)}
+}
+
+func (e *indexedEntityT) WithOptions(o Options) IndexedEntityTable {
+	indexes := make([]MultimapTable, len(e.indexes))
+	for i, index := range e.indexes {
+		indexes[i] = index.WithOptions(o)
+	}
+	return newIndexedEntityT(e.name, e.idField, e.indexFields, e.rowType, e.primary.WithOptions(o), indexes)
+}
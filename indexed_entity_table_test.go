@@ -0,0 +1,122 @@
+package gocassa
+
+import (
+	"testing"
+	"time"
+)
+
+type Member struct {
+	Id      string
+	Email   string
+	Country string
+	Name    string
+}
+
+func TestIndexedEntityTable(t *testing.T) {
+	tbl := ns.IndexedEntityTable("members", "Id", []string{"Email", "Country"}, Member{})
+	createIf(tbl, t)
+	jane := Member{Id: "1", Email: "jane@example.com", Country: "uk", Name: "Jane"}
+	john := Member{Id: "2", Email: "john@example.com", Country: "uk", Name: "John"}
+	for _, member := range []Member{jane, john} {
+		if err := tbl.Set(member).Run(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	list := func(field string, v interface{}) []Member {
+		members := []Member{}
+		if err := tbl.List(field, v, nil, 10, &members).Run(); err != nil {
+			t.Fatal(err)
+		}
+		return members
+	}
+	if members := list("Country", "uk"); len(members) != 2 {
+		t.Fatal(members)
+	}
+
+	// Changing an indexed field moves the index row
+	jane.Email = "jane@example.org"
+	if err := tbl.Set(jane).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if members := list("Email", "jane@example.com"); len(members) != 0 {
+		t.Fatal(members)
+	}
+	if members := list("Email", "jane@example.org"); len(members) != 1 || members[0] != jane {
+		t.Fatal(members)
+	}
+
+	// Updating moves the rows of the indexes whose field changes, and updates the others
+	if err := tbl.Update("2", map[string]interface{}{"Country": "fr", "Name": "Jean"}).Run(); err != nil {
+		t.Fatal(err)
+	}
+	john.Country, john.Name = "fr", "Jean"
+	if members := list("Country", "uk"); len(members) != 1 || members[0] != jane {
+		t.Fatal(members)
+	}
+	if members := list("Country", "fr"); len(members) != 1 || members[0] != john {
+		t.Fatal(members)
+	}
+	if members := list("Email", "john@example.com"); len(members) != 1 || members[0] != john {
+		t.Fatal(members)
+	}
+	member := Member{}
+	if err := tbl.Read("2", &member).Run(); err != nil || member != john {
+		t.Fatal(member, err)
+	}
+
+	if err := tbl.Delete("2").Run(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tbl.Delete("2").Run(); err != nil {
+		t.Fatal(err)
+	}
+	members := []Member{}
+	if err := tbl.MultiRead([]interface{}{"1", "2"}, &members).Run(); err != nil || len(members) != 1 {
+		t.Fatal(members, err)
+	}
+	if members := list("Country", "fr"); len(members) != 0 {
+		t.Fatal(members)
+	}
+	if members := list("Email", "john@example.com"); len(members) != 0 {
+		t.Fatal(members)
+	}
+}
+
+func TestIndexedEntityTableErrors(t *testing.T) {
+	tbl := ns.IndexedEntityTable("badmembers", "Id", []string{"Email"}, Member{})
+	createIf(tbl, t)
+	if _, ok := tbl.Update("1", map[string]interface{}{"Name": "Jane"}).Run().(RowNotFoundError); !ok {
+		t.Fatal("Expected updating a missing entity to fail")
+	}
+	if err := tbl.Update("1", map[string]interface{}{"Id": "2"}).Run(); err == nil {
+		t.Fatal("Expected updating the id to fail")
+	}
+	if err := tbl.Update("1", map[string]interface{}{"Email": ListAppend("jane@example.com")}).Run(); err == nil {
+		t.Fatal("Expected updating an indexed field with a modifier to fail")
+	}
+	members := []Member{}
+	if err := tbl.List("Name", "Jane", nil, 10, &members).Run(); err == nil {
+		t.Fatal("Expected listing by a field which is not indexed to fail")
+	}
+}
+
+func TestSameKeyValue(t *testing.T) {
+	now := time.Date(2020, 2, 29, 10, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		a, b interface{}
+		same bool
+	}{
+		{nil, nil, true},
+		{nil, "jane@example.com", false},
+		{"jane@example.com", nil, false},
+		{"jane@example.com", "jane@example.com", true},
+		{"jane@example.com", "jane@example.org", false},
+		{now, now.Add(time.Microsecond), true},
+		{now, now.Add(time.Millisecond), false},
+		{int64(1), 1, true},
+	} {
+		if sameKeyValue(c.a, c.b) != c.same {
+			t.Errorf("Expected sameKeyValue(%v, %v) to be %t", c.a, c.b, c.same)
+		}
+	}
+}
@@ -1,15 +1,29 @@
 package gocassa
 
 import (
+	"context"
 	"time"
+
+	"github.com/gocql/gocql"
 )
 
 // Connection exists because one can not connect to a keyspace if it does not exist, thus having a Create on KeySpace is not possible.
 // Use ConnectToKeySpace to acquire an instance of KeySpace without getting a Connection.
 type Connection interface {
 	CreateKeySpace(name string) error
+	// CreateKeySpaceWithOptions creates a keyspace with the given replication, see KeySpaceOptions
+	CreateKeySpaceWithOptions(name string, opts KeySpaceOptions) error
+	// CreateKeySpaceIfNotExists is like CreateKeySpaceWithOptions, but does nothing if the keyspace exists already
+	CreateKeySpaceIfNotExists(name string, opts KeySpaceOptions) error
+	// AlterKeySpace changes the replication of an existing keyspace. Unlike on creation, the replication is not
+	// defaulted: either the Strategy or the DatacenterRF of the options must be set, and SimpleStrategy needs a
+	// ReplicationFactor.
+	AlterKeySpace(name string, opts KeySpaceOptions) error
 	DropKeySpace(name string) error
 	KeySpace(name string) KeySpace
+	// WithQueryObserver returns a copy of the connection which calls the observer after every statement it executes,
+	// including the ones of its keyspaces
+	WithQueryObserver(QueryObserver) Connection
 	Close()
 }
 
@@ -22,15 +36,57 @@ type KeySpace interface {
 	MultiTimeSeriesTable(tableName, fieldToIndexByField, timeField, uniqueKey string, bucketSize time.Duration, row interface{}) MultiTimeSeriesTable
 	FlexMultiTimeSeriesTable(name, timeField, idField string, indexFields []string, bucketer Bucketer, row interface{}) MultiTimeSeriesTable
 	Table(tableName string, row interface{}, keys Keys) Table
+	// TableFromStruct is like Table, but the keys are derived from the tag options of the fields of the row:
+	// "partition" for partition keys and "clustering" for clustering columns, both in the order of the fields,
+	// optionally followed by "asc" or "desc" for the clustering order, and "static" for static columns. Eg.
+	//
+	//	type Event struct {
+	//		UserId  string    `cql:"user_id,partition"`
+	//		Created time.Time `cql:"created,clustering,desc"`
+	//		Owner   string    `cql:"owner,static"`
+	//		Payload string    `cql:"payload"`
+	//	}
+	TableFromStruct(tableName string, row interface{}) (Table, error)
+	// CounterTable returns a table of counters having the given keys. All the other fields of the row have to be
+	// of type Counter, as Cassandra does not allow tables with counter columns to hold anything else.
+	CounterTable(tableName string, keys Keys, row interface{}) (CounterTable, error)
+	// SortedSetTable returns a table of sets whose members are ordered by score, eg. leaderboards
+	SortedSetTable(tableName string) SortedSetTable
+	// QueueTable returns a durable queue of rows, eg. the outbox of a service. The idField of the rows must be a
+	// time based gocql.UUID, as the items are ordered and bucketed by the time of their id. The items are spread across
+	// the given number of shards so that enqueueing does not write to a single hot partition.
+	QueueTable(tableName, idField string, shards int, bucketSize time.Duration, row interface{}) QueueTable
+	// LeaseTable returns a table of leases, eg. to elect leaders or to exclude workers from each other
+	LeaseTable(tableName string) LeaseTable
+	// IndexedEntityTable returns a table of entities by id, kept in sync with a table listing them by each of the
+	// indexFields
+	IndexedEntityTable(tableName, idField string, indexFields []string, row interface{}) IndexedEntityTable
+	// CreateType creates the user defined type of a struct, and the ones of its fields, if they do not exist yet.
+	// Tables create the types of their fields when they are created, so this is only needed for types which are
+	// not used by the tables of gocassa.
+	CreateType(row interface{}) error
+	// CreateTypeStatements returns the statements CreateType executes, which can be used to create the types manually
+	// in cqlsh
+	CreateTypeStatements(row interface{}) ([]string, error)
+	// ExecuteContext executes a CQL statement in the keyspace, eg. a schema change gocassa does not generate. The
+	// mock keyspace does not interpret CQL, so it returns an error.
+	ExecuteContext(ctx context.Context, stmt string, params ...interface{}) error
 	// DebugMode enables/disables debug mode depending on the value of the input boolean.
-	// When DebugMode is enabled, all built CQL statements are printe to stdout.
+	// When DebugMode is enabled, all executed CQL statements are printed to stdout.
 	DebugMode(bool)
+	// WithQueryObserver returns a copy of the keyspace which calls the observer after every statement it executes.
+	// The mock keyspace does not execute statements, so its observers are never called.
+	WithQueryObserver(QueryObserver) KeySpace
 	// Name returns the keyspace name as in C*
 	Name() string
 	// Tables returns the name of all configured column families in this keyspace
 	Tables() ([]string, error)
 	// Exists returns whether the specified column family exists within the keyspace
 	Exists(string) (bool, error)
+	// TableSchema returns the columns, keys and clustering order of a table as defined in the cluster, or nil if the
+	// table does not exist. The schema tables of Cassandra 3.0+ (system_schema) are used, or the ones of older
+	// versions depending on the version of the cluster. Tables of the mock keyspace exist once they are created.
+	TableSchema(name string) (*TableSchema, error)
 }
 
 //
@@ -59,6 +115,8 @@ type MultimapTable interface {
 	Delete(v, id interface{}) Op
 	DeleteAll(v interface{}) Op
 	List(v, startId interface{}, limit int, pointerToASlice interface{}) Op
+	// ListIter is the streaming version of List
+	ListIter(v, startId interface{}, limit int) Iterator
 	Read(v, id, pointer interface{}) Op
 	MultiRead(v interface{}, ids []interface{}, pointerToASlice interface{}) Op
 	WithOptions(Options) MultimapTable
@@ -72,6 +130,8 @@ type MultimapMkTable interface {
 	Delete(v, id map[string]interface{}) Op
 	DeleteAll(v map[string]interface{}) Op
 	List(v, startId map[string]interface{}, limit int, pointerToASlice interface{}) Op
+	// ListIter is the streaming version of List
+	ListIter(v, startId map[string]interface{}, limit int) Iterator
 	Read(v, id map[string]interface{}, pointer interface{}) Op
 	MultiRead(v, id map[string]interface{}, pointerToASlice interface{}) Op
 	WithOptions(Options) MultimapMkTable
@@ -90,6 +150,8 @@ type TimeSeriesTable interface {
 	Delete(timeStamp time.Time, id interface{}) Op
 	Read(timeStamp time.Time, id, pointer interface{}) Op
 	List(start, end time.Time, pointerToASlice interface{}) Op
+	// ListIter is the streaming version of List
+	ListIter(start, end time.Time) Iterator
 	WithOptions(Options) TimeSeriesTable
 	TableChanger
 }
@@ -106,10 +168,145 @@ type MultiTimeSeriesTable interface {
 	Delete(v interface{}, timeStamp time.Time, id interface{}) Op
 	Read(v interface{}, timeStamp time.Time, id, pointer interface{}) Op
 	List(v interface{}, start, end time.Time, pointerToASlice interface{}) Op
+	// ListIter is the streaming version of List
+	ListIter(v interface{}, start, end time.Time) Iterator
 	WithOptions(Options) MultiTimeSeriesTable
 	TableChanger
 }
 
+//
+// Counter recipe
+//
+
+// CounterTable holds counters, eg. the views of pages per day, which are incremented rather than overwritten.
+// Rows are identified by maps holding the values of their key fields, eg. {"Page": "/", "Day": "2020-02-29"}.
+type CounterTable interface {
+	// Increment adds the deltas to the counters of the row, by field name. Negative deltas decrement the counters.
+	// Counters which were never incremented are read as zero.
+	Increment(key map[string]interface{}, deltas map[string]int) Op
+	Read(key map[string]interface{}, pointer interface{}) Op
+	// MultiRead reads the counters of several rows, whose keys may only differ by the value of a single field.
+	MultiRead(keys []map[string]interface{}, pointerToASlice interface{}) Op
+	// Reset deletes the counters of the row. Be aware that Cassandra does not reliably support incrementing deleted
+	// counters again, so rows should not be reused once reset.
+	Reset(key map[string]interface{}) Op
+	WithOptions(Options) CounterTable
+	TableChanger
+}
+
+//
+// Sorted set recipe
+//
+
+// SortedSetTable holds sets of members ordered by score, highest first, eg. the leaderboards of games. It is made of
+// a table holding the members of each set ordered by score and of a table holding the score of each member, which
+// are kept consistent by updating them in logged batches. Add, Score, Rank and Remove read the score of the member
+// before writing, so their statements are only known when they are run. Add and Remove can be part of other batches,
+// their writes being added to the batch once the score is read. Score and Rank can not be run in a batch.
+//
+// The score is read without a lightweight transaction. Concurrent Adds or Removes of the same member may each read
+// the same old score, in which case only one of the score rows it was ordered by gets deleted, and the member is then
+// listed several times. Writes to a member should not be made concurrently, eg. by sharding the writers by member.
+type SortedSetTable interface {
+	// Add adds the member to the set, or changes its score if it is a member already
+	Add(set, member string, score float64) Op
+	// Score reads the score of the member. It fails with a RowNotFoundError if it is not a member of the set.
+	Score(set, member string, score *float64) Op
+	// Rank reads the rank of the member, the member with the highest score being ranked 0. Members with the same
+	// score are ranked by name. It fails with a RowNotFoundError if it is not a member of the set. The members
+	// ranked before it are read to count them, so ranking a member costs as much as reading its rank of rows.
+	Rank(set, member string, rank *int) Op
+	// Top reads the n members having the highest scores
+	Top(set string, n int, members *[]SortedSetMember) Op
+	// RangeByScore reads the members having a score between min and max included, highest first
+	RangeByScore(set string, min, max float64, members *[]SortedSetMember) Op
+	// Remove removes the member from the set, if it is a member
+	Remove(set, member string) Op
+	WithOptions(Options) SortedSetTable
+	TableChanger
+}
+
+// SortedSetMember is a member of a sorted set and its score
+type SortedSetMember struct {
+	Member string
+	Score  float64
+}
+
+//
+// Queue recipe
+//
+
+// QueueTable is a durable queue, eg. an outbox of events to publish. Items are removed from the queue once they are
+// acknowledged, so that items which failed to be processed are peeked again. Peek reads every shard of the queue, the
+// buckets which are over and emptied are removed from the index of the queue when they are peeked.
+//
+// Items are usually enqueued in the current bucket, but an item may be enqueued again with an older id, eg. when
+// retrying. Its bucket is then indexed again, and Peek removes a bucket from the index as of the time it read it
+// empty, so that the bucket stays indexed when the item is enqueued after it was read. An item enqueued while Peek
+// reads its bucket, whose index row is written before the read and the item after it, may still be removed from the
+// index along with its bucket; it is not peeked until it is enqueued again. Clock skew between the clients widens
+// that window.
+type QueueTable interface {
+	// Enqueue adds the row to the queue. Its id field must be a time based gocql.UUID, eg. gocql.TimeUUID().
+	Enqueue(v interface{}) Op
+	// Peek reads the n oldest items of the queue, without removing them
+	Peek(n int, pointerToASlice interface{}) Op
+	// Ack removes the item having the given id from the queue
+	Ack(id gocql.UUID) Op
+	WithOptions(Options) QueueTable
+	TableChanger
+}
+
+//
+// Lease recipe
+//
+
+// LeaseTable holds named leases, which are held by a single owner until they are released or they expire. Leases are
+// written with lightweight transactions, so an owner only acquires a lease which no one else holds. Each operation
+// reports whether it took place through its bool pointer, which may be nil. Owners have to renew their leases before
+// they expire, and they should stop acting as holders if renewing fails.
+type LeaseTable interface {
+	// Acquire acquires the lease for the ttl if no one holds it (INSERT ... IF NOT EXISTS USING TTL). If the owner
+	// holds it already, the lease is renewed.
+	Acquire(name, owner string, ttl time.Duration, acquired *bool) Op
+	// Renew extends the lease to expire after the ttl, if the owner still holds it
+	Renew(name, owner string, ttl time.Duration, renewed *bool) Op
+	// Release releases the lease, if the owner holds it
+	Release(name, owner string, released *bool) Op
+	// Holder reads the owner holding the lease, or an empty string if no one holds it
+	Holder(name string, owner *string) Op
+	WithOptions(Options) LeaseTable
+	TableChanger
+}
+
+//
+// Indexed entity recipe
+//
+
+// IndexedEntityTable holds entities in a MapTable by id, and in a MultimapTable for each of its indexed fields. Set,
+// Update and Delete read the entity before writing, to delete the index rows pointing to the old values of the
+// indexed fields, and write all the tables in a logged batch. Their statements are only known when they are run,
+// when they are part of other batches their writes are added to the batch once the entity is read.
+//
+// The entity is read without a lightweight transaction. Concurrent writes to the same entity may each read the same
+// old values, in which case the index rows pointing to the values written by the others are not deleted, and List
+// returns the entity for values it no longer has. Writes to an entity should not be made concurrently.
+type IndexedEntityTable interface {
+	Set(v interface{}) Op
+	// Update updates the fields of the entity, moving its index rows if indexed fields change. Indexed fields can
+	// not be updated with modifiers, nor other fields while an indexed field changes. It fails with a
+	// RowNotFoundError if there is no entity with the id.
+	Update(id interface{}, m map[string]interface{}) Op
+	// Delete deletes the entity and its index rows, if it exists
+	Delete(id interface{}) Op
+	Read(id, pointer interface{}) Op
+	MultiRead(ids []interface{}, pointerToASlice interface{}) Op
+	// List lists the entities whose indexed field has the value v, like MultimapTable.List
+	List(field string, v, startId interface{}, limit int, pointerToASlice interface{}) Op
+	WithOptions(Options) IndexedEntityTable
+	TableChanger
+}
+
 //
 // Raw CQL
 //
@@ -125,6 +322,39 @@ type Filter interface {
 	Read(pointerToASlice interface{}) Op
 	// Read one result. Make sure you pass in a pointer.
 	ReadOne(pointer interface{}) Op
+	// UpdateIf does a partial update of a single row, but only if all of the conditions hold (IF col = ? AND ...).
+	// Without conditions the row only has to exist (IF EXISTS). If result is not nil, it is filled in with the
+	// outcome of the lightweight transaction.
+	UpdateIf(conditions []Relation, m map[string]interface{}, result *CASResult) Op
+	// DeleteIf deletes a single row, but only if all of the conditions hold. Without conditions the row only has
+	// to exist. If result is not nil, it is filled in with the outcome of the lightweight transaction.
+	DeleteIf(conditions []Relation, result *CASResult) Op
+	// Iter returns an Iterator over the results. Unlike Read, rows are fetched page by page as the iterator
+	// advances instead of being loaded into memory at once.
+	Iter() Iterator
+	// IterContext is the context aware version of Iter.
+	IterContext(ctx context.Context) Iterator
+}
+
+// Iterator is used to stream the results of a read. It must be closed once you are done with it.
+// For example:
+//
+//	iter := table.Where(Eq("Id", 1)).Iter()
+//	defer iter.Close()
+//	var row Row
+//	for iter.Next(&row) {
+//	    ...
+//	}
+//	if err := iter.Err(); err != nil {
+//	    ...
+//	}
+type Iterator interface {
+	// Next decodes the next row into the pointer. It returns false if there are no more rows or an error occurred.
+	Next(pointer interface{}) bool
+	// Err returns the error which stopped the iteration, if any
+	Err() error
+	// Close releases the underlying resources and returns the error which stopped the iteration, if any
+	Close() error
 }
 
 // Keys is used with the raw CQL Table type. It is implicit when using recipe tables.
@@ -132,6 +362,8 @@ type Keys struct {
 	PartitionKeys     []string
 	ClusteringColumns []string
 	Compound          bool //indicates if the partitions keys are gereated as compound key when no clustering columns are set
+	// StaticColumns are shared by all the rows of a partition, they require clustering columns
+	StaticColumns []string
 }
 
 // Op is returned by both read and write methods, you have to run them explicitly to take effect.
@@ -139,9 +371,28 @@ type Keys struct {
 type Op interface {
 	// Run the operation.
 	Run() error
+	// RunContext runs the operation. The context is passed down to the QueryExecutor so cancellation and
+	// deadlines abort the underlying queries.
+	RunContext(ctx context.Context) error
 	// You do not need this in 95% of the use cases, use Run!
 	// Using atomic batched writes (logged batches in Cassandra terminology) comes at a high performance cost!
 	RunAtomically() error
+	// RunAtomicallyContext is the context aware version of RunAtomically.
+	RunAtomicallyContext(ctx context.Context) error
+	// RunBatch runs the operation as a batch of the given type. RunAtomically is the same as RunBatch(LoggedBatch).
+	// Use UnloggedBatch to group writes to the same partition, and CounterBatch for counter updates, which can not
+	// be part of logged batches. It fails if the operation holds ops which read or write as they are run, eg. the
+	// ones of the lease recipe, instead of running them one after the other. Conditional writes can not be batched
+	// with other ops either.
+	RunBatch(batchType BatchType) error
+	// RunBatchContext is the context aware version of RunBatch.
+	RunBatchContext(ctx context.Context, batchType BatchType) error
+	// RunParallel runs the operations concurrently, at most concurrency of them at a time (all of them at once if
+	// concurrency is less than 1). Unlike Run it does not stop at the first failure, it returns a MultiError
+	// holding the errors of all the failed operations instead.
+	RunParallel(concurrency int) error
+	// RunParallelContext is the context aware version of RunParallel.
+	RunParallelContext(ctx context.Context, concurrency int) error
 	// Add an other Op to this one.
 	Add(...Op) Op
 	// WithOptions lets you specify `Op` level `Options`.
@@ -155,7 +406,7 @@ type Op interface {
 	//
 	WithOptions(Options) Op
 	// Preflight performs any pre-execution validation that confirms the op considers itself "valid".
-	// NOTE: Run() and RunAtomically() should call this method before execution, and abort if any errors are returned.
+	// NOTE: Run() and RunAtomically() (and their Context variants) should call this method before execution, and abort if any errors are returned.
 	Preflight() error
 	// GenerateStatement generates the statment and params to perform the operation
 	GenerateStatement() (string, []interface{})
@@ -168,7 +419,8 @@ type TableChanger interface {
 	// Create creates the table in the keySpace, but only if it does not exist already.
 	// If the table already exists, it returns an error.
 	Create() error
-	// CreateStatement returns you the CQL query which can be used to create the table manually in cqlsh
+	// CreateStatement returns you the CQL query which can be used to create the table manually in cqlsh.
+	// The user defined types of its fields are not part of it, see KeySpace.CreateTypeStatements.
 	CreateStatement() (string, error)
 	// Create creates the table in the keySpace, but only if it does not exist already.
 	// If the table already exists, then nothing is created.
@@ -178,6 +430,16 @@ type TableChanger interface {
 	// Recreate drops the table if exists and creates it again.
 	// This is useful for test purposes only.
 	Recreate() error
+	// SyncStatements compares the table with its live schema and returns the ALTER TABLE statements adding the
+	// columns it lacks, or the statement creating it if it does not exist. Changes to the type of columns or to the
+	// primary key can not be applied this way and are returned as errors. Columns are never dropped.
+	SyncStatements() ([]string, error)
+	// Sync executes the statements returned by SyncStatements
+	Sync() error
+	// AlterStatement returns the ALTER TABLE statement setting the properties of Options.Properties on the table
+	AlterStatement() (string, error)
+	// Alter sets the properties of Options.Properties on the existing table
+	Alter() error
 	// Name returns the name of the table, as in C*
 	Name() string
 	//Drop() error
@@ -191,6 +453,9 @@ type Table interface {
 	// Set Inserts, or Replaces your row with the supplied struct. Be aware that what is not in your struct
 	// will be deleted. To only overwrite some of the fields, use Query.Update.
 	Set(v interface{}) Op
+	// SetIfNotExists inserts your row only if there is no row with the same primary key yet (INSERT ... IF NOT EXISTS).
+	// If result is not nil, it is filled in with the outcome of the lightweight transaction.
+	SetIfNotExists(v interface{}, result *CASResult) Op
 	// Where accepts a bunch of realtions and returns a filter. See the documentation for Relation and Filter to understand what that means.
 	Where(relations ...Relation) Filter // Because we provide selections
 	// Name returns the underlying table name, as stored in C*
@@ -201,7 +466,8 @@ type Table interface {
 // QueryExecutor actually executes the queries - this is mostly useful for testing/mocking purposes,
 // ignore this otherwise. This library is using github.com/gocql/gocql as the query executor by default.
 type QueryExecutor interface {
-	// Query executes a query and returns the results.  It also takes Options to do things like set consistency
+	// Query executes a query and returns the results.  It also takes Options to do things like set consistency.
+	// If Options.Paging is set only a single page is returned, and Options.Paging.State is updated to point to the next one.
 	QueryWithOptions(opts Options, stmt string, params ...interface{}) ([]map[string]interface{}, error)
 	// Query executes a query and returns the results
 	Query(stmt string, params ...interface{}) ([]map[string]interface{}, error)
@@ -211,8 +477,41 @@ type QueryExecutor interface {
 	Execute(stmt string, params ...interface{}) error
 	// ExecuteAtomically executs multiple DML queries with a logged batch
 	ExecuteAtomically(stmt []string, params [][]interface{}) error
+	// QueryContext is the same as QueryWithOptions, but the query is bound to the given context
+	QueryContext(ctx context.Context, opts Options, stmt string, params ...interface{}) ([]map[string]interface{}, error)
+	// ExecuteContext is the same as ExecuteWithOptions, but the query is bound to the given context
+	ExecuteContext(ctx context.Context, opts Options, stmt string, params ...interface{}) error
+	// ExecuteAtomicallyContext is the same as ExecuteAtomically, but the batch is bound to the given context
+	ExecuteAtomicallyContext(ctx context.Context, stmt []string, params [][]interface{}) error
+	// ExecuteBatch executes multiple DML queries with a batch of the given type
+	ExecuteBatch(ctx context.Context, batchType BatchType, stmt []string, params [][]interface{}) error
+	// QueryIter executes a query and returns an iterator over the raw rows, fetching them lazily
+	QueryIter(ctx context.Context, opts Options, stmt string, params ...interface{}) RowIterator
+	// ExecuteCAS executes a conditional DML query (lightweight transaction) and returns whether it was applied.
+	// If it was not, current is filled with the values of the existing row.
+	ExecuteCAS(ctx context.Context, opts Options, current map[string]interface{}, stmt string, params ...interface{}) (bool, error)
 	// Close closes the open session
 	Close()
 }
 
+// BatchType is the type of the batch used to execute multiple queries. The values match the ones of gocql.
+type BatchType byte
+
+const (
+	// LoggedBatch makes the writes of the batch atomic, at the cost of performance
+	LoggedBatch BatchType = iota
+	// UnloggedBatch applies the writes without the atomicity guarantee, ideal for writes to a single partition
+	UnloggedBatch
+	// CounterBatch is used to batch counter updates
+	CounterBatch
+)
+
+// RowIterator iterates over the raw rows returned by a QueryExecutor. A *gocql.Iter satisfies this interface.
+type RowIterator interface {
+	// MapScan fills the map with the next row. It returns false if there are no more rows or an error occurred.
+	MapScan(m map[string]interface{}) bool
+	// Close releases the underlying resources and returns the error which stopped the iteration, if any
+	Close() error
+}
+
 type Counter int
@@ -0,0 +1,64 @@
+package gocassa
+
+// iterator decodes rows coming from a RowIterator one by one, so a read does not need to hold the whole
+// result set in memory.
+type iterator struct {
+	rows   RowIterator
+	err    error
+	closed bool
+}
+
+func newIterator(rows RowIterator) *iterator {
+	return &iterator{
+		rows: rows,
+	}
+}
+
+func (it *iterator) Next(pointer interface{}) bool {
+	if it.closed {
+		return false
+	}
+	m := map[string]interface{}{}
+	if !it.rows.MapScan(m) {
+		it.Close()
+		return false
+	}
+	if err := decodeResult(m, pointer); err != nil {
+		it.err = err
+		it.Close()
+		return false
+	}
+	return true
+}
+
+func (it *iterator) Err() error {
+	return it.err
+}
+
+func (it *iterator) Close() error {
+	if it.closed {
+		return it.err
+	}
+	it.closed = true
+	if err := it.rows.Close(); err != nil && it.err == nil {
+		it.err = err
+	}
+	return it.err
+}
+
+// Used to pass errors back through the fluent API, like badOp
+type badIterator struct {
+	err error
+}
+
+func (it *badIterator) Next(pointer interface{}) bool {
+	return false
+}
+
+func (it *badIterator) Err() error {
+	return it.err
+}
+
+func (it *badIterator) Close() error {
+	return it.err
+}
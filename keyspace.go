@@ -1,9 +1,13 @@
 package gocassa
 
 import (
+	"context"
 	"fmt"
+	"reflect"
 	"strings"
 	"time"
+
+	r "github.com/gocassa/gocassa/reflect"
 )
 
 type tableFactory interface {
@@ -15,6 +19,7 @@ type k struct {
 	name         string
 	debugMode    bool
 	tableFactory tableFactory
+	schema       *schemaFlavour // shared by the copies of the keyspace
 }
 
 // Connect to a certain keyspace directly. Same as using Connect().KeySpace(keySpaceName)
@@ -30,6 +35,25 @@ func (k *k) DebugMode(b bool) {
 	k.debugMode = b
 }
 
+func (k *k) WithQueryObserver(observer QueryObserver) KeySpace {
+	cpy := *k
+	if cpy.qe != nil {
+		cpy.qe = ObserveQueryExecutor(cpy.qe, observer)
+	}
+	if k.tableFactory == k {
+		cpy.tableFactory = &cpy
+	}
+	return &cpy
+}
+
+// queryExecutor returns the QueryExecutor the statements of the keyspace go through, which logs them in debug mode
+func (k *k) queryExecutor() QueryExecutor {
+	if k.debugMode && k.qe != nil {
+		return ObserveQueryExecutor(k.qe, debugLogger)
+	}
+	return k.qe
+}
+
 func (k *k) Table(name string, entity interface{}, keys Keys) Table {
 	n := name + "__" + strings.Join(keys.PartitionKeys, "_") + "__" + strings.Join(keys.ClusteringColumns, "_")
 	m, ok := toMap(entity)
@@ -39,6 +63,86 @@ func (k *k) Table(name string, entity interface{}, keys Keys) Table {
 	return k.NewTable(n, entity, m, keys)
 }
 
+func (k *k) TableFromStruct(name string, row interface{}) (Table, error) {
+	keys, order, err := structKeys(row)
+	if err != nil {
+		return nil, err
+	}
+	tbl := k.Table(name, row, keys)
+	if len(order) > 0 {
+		tbl = tbl.WithOptions(Options{ClusteringOrder: order})
+	}
+	return tbl, nil
+}
+
+// structKeys derives the keys and the clustering order of a table from the tag options of the fields of the row
+func structKeys(row interface{}) (Keys, []ClusteringOrderColumn, error) {
+	keys := Keys{}
+	if _, ok := toMap(row); !ok {
+		return keys, nil, fmt.Errorf("Unrecognized row type %T", row)
+	}
+
+	order := []ClusteringOrderColumn{}
+	explicitOrder := false
+	for _, field := range r.TaggedFields(row) {
+		var partition, clustering, static, asc, desc bool
+		for _, opt := range field.Options {
+			switch opt {
+			case "partition":
+				partition = true
+			case "clustering":
+				clustering = true
+			case "static":
+				static = true
+			case "asc":
+				asc = true
+			case "desc":
+				desc = true
+			case "omitempty", setOption, tupleOption, frozenOption, "":
+			default:
+				if strings.HasPrefix(opt, typeOption) {
+					continue
+				}
+				return keys, nil, fmt.Errorf("Unknown tag option %q on field %s", opt, field.Name)
+			}
+		}
+
+		switch {
+		case partition && (clustering || static):
+			return keys, nil, fmt.Errorf("Field %s can not be both a partition key and a clustering or static column", field.Name)
+		case clustering && static:
+			return keys, nil, fmt.Errorf("Field %s can not be both a clustering and a static column", field.Name)
+		case (asc || desc) && !clustering:
+			return keys, nil, fmt.Errorf("Field %s has a clustering order but is not a clustering column", field.Name)
+		case asc && desc:
+			return keys, nil, fmt.Errorf("Field %s can not be ordered both ascending and descending", field.Name)
+		case partition:
+			keys.PartitionKeys = append(keys.PartitionKeys, field.Name)
+		case clustering:
+			keys.ClusteringColumns = append(keys.ClusteringColumns, field.Name)
+			direction := ASC
+			if desc {
+				direction = DESC
+			}
+			explicitOrder = explicitOrder || asc || desc
+			order = append(order, ClusteringOrderColumn{Column: field.Name, Direction: direction})
+		case static:
+			keys.StaticColumns = append(keys.StaticColumns, field.Name)
+		}
+	}
+
+	if len(keys.PartitionKeys) == 0 {
+		return keys, nil, fmt.Errorf("%T has no partition key, tag at least one field with the partition option", row)
+	}
+	if len(keys.StaticColumns) > 0 && len(keys.ClusteringColumns) == 0 {
+		return keys, nil, fmt.Errorf("%T has static columns but no clustering column", row)
+	}
+	if !explicitOrder {
+		order = nil
+	}
+	return keys, order, nil
+}
+
 func (k *k) NewTable(name string, entity interface{}, fields map[string]interface{}, keys Keys) Table {
 	// Act both as a proxy to a tableFactory, and as the tableFactory itself (in most situations, a k will be its own
 	// tableFactory, but not always [ie. mocking])
@@ -67,6 +171,80 @@ func (k *k) MapTable(name, id string, row interface{}) MapTable {
 	}
 }
 
+func (k *k) CounterTable(name string, keys Keys, row interface{}) (CounterTable, error) {
+	m, ok := toMap(row)
+	if !ok {
+		return nil, fmt.Errorf("Unrecognized row type %T", row)
+	}
+	if err := checkCounterFields(m, keys); err != nil {
+		return nil, err
+	}
+	keyFields := append(append([]string{}, keys.PartitionKeys...), keys.ClusteringColumns...)
+	return &counterT{
+		Table:     k.NewTable(fmt.Sprintf("%s_counter_%s", name, strings.Join(keyFields, "_")), row, m, keys),
+		keyFields: keyFields,
+	}, nil
+}
+
+func (k *k) SortedSetTable(name string) SortedSetTable {
+	scores, _ := toMap(sortedSetScore{})
+	members, _ := toMap(sortedSetMember{})
+	return newSortedSetT(name,
+		k.NewTable(fmt.Sprintf("%s_sortedSet_scores", name), sortedSetScore{}, scores, Keys{
+			PartitionKeys:     []string{"SetName"},
+			ClusteringColumns: []string{"Score", "Member"},
+		}).WithOptions(Options{}.AppendClusteringOrder("Score", DESC).AppendClusteringOrder("Member", ASC)),
+		k.NewTable(fmt.Sprintf("%s_sortedSet_members", name), sortedSetMember{}, members, Keys{
+			PartitionKeys:     []string{"SetName"},
+			ClusteringColumns: []string{"Member"},
+		}),
+	)
+}
+
+func (k *k) QueueTable(name, idField string, shards int, bucketSize time.Duration, row interface{}) QueueTable {
+	m, ok := toMap(row)
+	if !ok {
+		panic("Unrecognized row type")
+	}
+	if shards < 1 {
+		panic("Queue tables need at least one shard")
+	}
+	m[queueShardField] = 0
+	m[bucketFieldName] = time.Now()
+	m[queueTimeField] = time.Now()
+	buckets, _ := toMap(queueBucket{})
+	return newQueueT(name, idField, shards, bucketSize,
+		k.NewTable(fmt.Sprintf("%s_queue_%s_%d_%s", name, idField, shards, bucketSize), row, m, Keys{
+			PartitionKeys:     []string{queueShardField, bucketFieldName},
+			ClusteringColumns: []string{queueTimeField, idField},
+		}),
+		k.NewTable(fmt.Sprintf("%s_queue_%s_%d_%s_buckets", name, idField, shards, bucketSize), queueBucket{}, buckets, Keys{
+			PartitionKeys:     []string{"Shard"},
+			ClusteringColumns: []string{"Bucket"},
+		}),
+	)
+}
+
+func (k *k) LeaseTable(name string) LeaseTable {
+	m, _ := toMap(leaseRow{})
+	return &leaseT{
+		Table: k.NewTable(fmt.Sprintf("%s_lease", name), leaseRow{}, m, Keys{
+			PartitionKeys: []string{"Name"},
+		}),
+	}
+}
+
+func (k *k) IndexedEntityTable(name, idField string, indexFields []string, row interface{}) IndexedEntityTable {
+	if _, ok := toMap(row); !ok {
+		panic("Unrecognized row type")
+	}
+	indexes := make([]MultimapTable, len(indexFields))
+	for i, field := range indexFields {
+		indexes[i] = k.MultimapTable(name, field, idField, row)
+	}
+	return newIndexedEntityT(name, idField, indexFields, reflect.TypeOf(row), k.MapTable(name, idField, row), indexes)
+}
+
 func (k *k) SetKeysSpaceName(name string) {
 	k.name = name
 }
@@ -142,18 +320,28 @@ func (k *k) FlexMultiTimeSeriesTable(name, timeField, idField string, indexField
 	}
 }
 
-// Tables returns table names in a keyspace
-func (k *k) Tables() ([]string, error) {
-	const stmt = "SELECT columnfamily_name FROM system.schema_columnfamilies WHERE keyspace_name = ?"
-	maps, err := k.qe.Query(stmt, k.name)
+func (k *k) CreateType(row interface{}) error {
+	stmts, err := k.CreateTypeStatements(row)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	ret := []string{}
-	for _, m := range maps {
-		ret = append(ret, m["columnfamily_name"].(string))
+	for _, stmt := range stmts {
+		if err := k.queryExecutor().Execute(stmt); err != nil {
+			return err
+		}
 	}
-	return ret, nil
+	return nil
+}
+
+func (k *k) CreateTypeStatements(row interface{}) ([]string, error) {
+	if row == nil || !isUDT(reflect.TypeOf(row)) {
+		return nil, fmt.Errorf("Unsupported type %T, user defined types must be structs", row)
+	}
+	return createTypeStmts(k.name, []interface{}{row})
+}
+
+func (k *k) ExecuteContext(ctx context.Context, stmt string, params ...interface{}) error {
+	return k.queryExecutor().ExecuteContext(ctx, Options{}, stmt, params...)
 }
 
 func (k *k) Exists(cf string) (bool, error) {
@@ -171,7 +359,7 @@ func (k *k) Exists(cf string) (bool, error) {
 
 func (k *k) DropTable(cf string) error {
 	stmt := fmt.Sprintf("DROP TABLE IF EXISTS %s.%s", k.name, cf)
-	return k.qe.Execute(stmt)
+	return k.queryExecutor().Execute(stmt)
 }
 
 func (k *k) Name() string {
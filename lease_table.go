@@ -0,0 +1,119 @@
+package gocassa
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// leaseRow is a lease held by its owner, which expires with the TTL it was written with
+type leaseRow struct {
+	Name  string
+	Owner string
+}
+
+type leaseT struct {
+	Table
+}
+
+func checkLeaseTTL(ttl time.Duration) error {
+	if ttl < time.Second {
+		return fmt.Errorf("Leases need a TTL of at least a second, got %s", ttl)
+	}
+	return nil
+}
+
+// setApplied reports the outcome of a lightweight transaction into the pointer, which may be nil
+func setApplied(pointer *bool, applied bool) {
+	if pointer != nil {
+		*pointer = applied
+	}
+}
+
+// renew extends the lease if it is held by the owner, it reports whether it was
+func (l *leaseT) renew(ctx context.Context, opts Options, name, owner string, ttl time.Duration) (bool, error) {
+	result := CASResult{}
+	err := l.Where(Eq("Name", name)).
+		UpdateIf([]Relation{Eq("Owner", owner)}, map[string]interface{}{"Owner": owner}, &result).
+		WithOptions(opts).
+		WithOptions(Options{TTL: ttl}).
+		RunContext(ctx)
+	return result.Applied, err
+}
+
+func (l *leaseT) Acquire(name, owner string, ttl time.Duration, acquired *bool) Op {
+	if err := checkLeaseTTL(ttl); err != nil {
+		return &badOp{err}
+	}
+	return newDeferredOp(false, func(ctx context.Context, opts Options) (Op, error) {
+		result := CASResult{}
+		err := l.SetIfNotExists(leaseRow{Name: name, Owner: owner}, &result).
+			WithOptions(opts).
+			WithOptions(Options{TTL: ttl}).
+			RunContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		applied := result.Applied
+		if !applied {
+			// The owner may already hold the lease, in which case acquiring it again renews it
+			current := leaseRow{}
+			if err := result.Decode(&current); err != nil {
+				return nil, err
+			}
+			if current.Owner == owner {
+				if applied, err = l.renew(ctx, opts, name, owner, ttl); err != nil {
+					return nil, err
+				}
+			}
+		}
+		setApplied(acquired, applied)
+		return Noop(), nil
+	})
+}
+
+func (l *leaseT) Renew(name, owner string, ttl time.Duration, renewed *bool) Op {
+	if err := checkLeaseTTL(ttl); err != nil {
+		return &badOp{err}
+	}
+	return newDeferredOp(false, func(ctx context.Context, opts Options) (Op, error) {
+		applied, err := l.renew(ctx, opts, name, owner, ttl)
+		if err != nil {
+			return nil, err
+		}
+		setApplied(renewed, applied)
+		return Noop(), nil
+	})
+}
+
+func (l *leaseT) Release(name, owner string, released *bool) Op {
+	return newDeferredOp(false, func(ctx context.Context, opts Options) (Op, error) {
+		result := CASResult{}
+		err := l.Where(Eq("Name", name)).DeleteIf([]Relation{Eq("Owner", owner)}, &result).WithOptions(opts).RunContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		setApplied(released, result.Applied)
+		return Noop(), nil
+	})
+}
+
+func (l *leaseT) Holder(name string, owner *string) Op {
+	return newDeferredOp(false, func(ctx context.Context, opts Options) (Op, error) {
+		row := leaseRow{}
+		err := l.Where(Eq("Name", name)).ReadOne(&row).WithOptions(opts).RunContext(ctx)
+		if _, ok := err.(RowNotFoundError); ok {
+			*owner = ""
+			return Noop(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		*owner = row.Owner
+		return Noop(), nil
+	})
+}
+
+func (l *leaseT) WithOptions(o Options) LeaseTable {
+	return &leaseT{Table: l.Table.WithOptions(o)}
+}
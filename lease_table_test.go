@@ -0,0 +1,55 @@
+package gocassa
+
+import (
+	"testing"
+	"time"
+)
+
+// Expiry and contention are tested on the mock keyspace, whose clock can be moved forward (see MockSuite)
+func TestLeaseTable(t *testing.T) {
+	tbl := ns.LeaseTable("locks")
+	createIf(tbl, t)
+
+	var holder string
+	if err := tbl.Holder("leader", &holder).Run(); err != nil || holder != "" {
+		t.Fatal(holder, err)
+	}
+	var ok bool
+	if err := tbl.Acquire("leader", "a", time.Minute, &ok).Run(); err != nil || !ok {
+		t.Fatal(ok, err)
+	}
+	if err := tbl.Acquire("leader", "b", time.Minute, &ok).Run(); err != nil || ok {
+		t.Fatal("Expected b not to acquire the lease held by a", err)
+	}
+	if err := tbl.Holder("leader", &holder).Run(); err != nil || holder != "a" {
+		t.Fatal(holder, err)
+	}
+	if err := tbl.Renew("leader", "b", time.Minute, &ok).Run(); err != nil || ok {
+		t.Fatal("Expected b not to renew the lease held by a", err)
+	}
+	if err := tbl.Release("leader", "b", &ok).Run(); err != nil || ok {
+		t.Fatal("Expected b not to release the lease held by a", err)
+	}
+	if err := tbl.Renew("leader", "a", time.Minute, &ok).Run(); err != nil || !ok {
+		t.Fatal(ok, err)
+	}
+	if err := tbl.Acquire("leader", "a", time.Minute, &ok).Run(); err != nil || !ok {
+		t.Fatal("Expected a to acquire the lease it holds again", err)
+	}
+
+	if err := tbl.Release("leader", "a", &ok).Run(); err != nil || !ok {
+		t.Fatal(ok, err)
+	}
+	if err := tbl.Holder("leader", &holder).Run(); err != nil || holder != "" {
+		t.Fatal(holder, err)
+	}
+	if err := tbl.Acquire("leader", "b", time.Minute, &ok).Run(); err != nil || !ok {
+		t.Fatal(ok, err)
+	}
+	if err := tbl.Release("leader", "b", nil).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tbl.Acquire("leader", "a", time.Millisecond, nil).Run(); err == nil {
+		t.Fatal("Expected acquiring a lease for less than a second to fail")
+	}
+}
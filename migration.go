@@ -0,0 +1,247 @@
+package gocassa
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MigrationTableName is the name of the table the migrations applied to a keyspace are recorded in
+const MigrationTableName = "schema_migrations"
+
+// migrationScope is the partition all the migrations of a keyspace are recorded in
+const migrationScope = "gocassa"
+
+// Migration is a versioned change to the schema of a keyspace, made of either CQL statements or a Go function.
+// Migrations are applied in the order of their versions, and only once.
+type Migration struct {
+	Version     int
+	Description string
+	// CQL holds the statements of the migration, which are executed one by one
+	CQL []string
+	// Func is called to apply migrations which can not be expressed in CQL alone, eg. to backfill data. It must
+	// be idempotent, as a migration is only recorded once it returns.
+	Func func(ctx context.Context, ks KeySpace) error
+}
+
+// checksum identifies the content of a migration, so changes to migrations which were already applied are noticed.
+// The code of Go migrations can not be taken into account, only their description.
+func (m Migration) checksum() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\n%s\n", m.Version, m.Description)
+	for _, stmt := range m.CQL {
+		fmt.Fprintf(h, "%s\n", strings.TrimSpace(stmt))
+	}
+	if m.Func != nil {
+		fmt.Fprint(h, "func\n")
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// AppliedMigration is the record of a migration applied to a keyspace. AppliedAt is zero while the migration is
+// being applied.
+type AppliedMigration struct {
+	Scope       string    `cql:"scope"`
+	Version     int       `cql:"version"`
+	Description string    `cql:"description"`
+	Checksum    string    `cql:"checksum"`
+	AppliedAt   time.Time `cql:"applied_at"`
+}
+
+// MigrationHistoryError is returned when the migrations recorded in a keyspace do not match the known migrations,
+// eg. because a migration was modified after being applied. No migration is applied in this case.
+type MigrationHistoryError struct {
+	Version int
+	Reason  string
+}
+
+func (e MigrationHistoryError) Error() string {
+	return fmt.Sprintf("Migration history diverges at version %d: %s", e.Version, e.Reason)
+}
+
+// Migrator applies migrations to a keyspace, recording them in the MigrationTableName table of the keyspace.
+// CQL migrations are executed with KeySpace.ExecuteContext. The mock keyspace can not execute them, so only Go
+// migrations can be applied to it.
+//
+// Migrators running concurrently do not apply the same migration twice: each migration is claimed with a lightweight
+// transaction before being applied, and the other migrators fail. A migration whose migrator stopped while applying
+// it stays claimed, and is reported as a MigrationHistoryError until its record is deleted from the history table.
+type Migrator struct {
+	ks         KeySpace
+	table      Table
+	migrations []Migration
+}
+
+// NewMigrator returns a Migrator applying the migrations to the keyspace
+func NewMigrator(ks KeySpace, migrations ...Migration) *Migrator {
+	sorted := append([]Migration{}, migrations...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Version < sorted[j].Version
+	})
+	return &Migrator{
+		ks: ks,
+		table: ks.Table(MigrationTableName, AppliedMigration{}, Keys{
+			PartitionKeys:     []string{"scope"},
+			ClusteringColumns: []string{"version"},
+		}).WithOptions(Options{TableName: MigrationTableName}),
+		migrations: sorted,
+	}
+}
+
+// Applied returns the migrations recorded in the keyspace, in the order of their versions
+func (m *Migrator) Applied() ([]AppliedMigration, error) {
+	return m.applied(context.Background())
+}
+
+func (m *Migrator) applied(ctx context.Context) ([]AppliedMigration, error) {
+	applied := []AppliedMigration{}
+	// No migration was applied yet if the history table does not exist
+	if exists, err := m.ks.Exists(MigrationTableName); err != nil || !exists {
+		return applied, err
+	}
+	if err := m.table.Where(Eq("scope", migrationScope)).Read(&applied).RunContext(ctx); err != nil {
+		return nil, err
+	}
+	sort.Slice(applied, func(i, j int) bool {
+		return applied[i].Version < applied[j].Version
+	})
+	return applied, nil
+}
+
+// Pending returns the migrations which are yet to be applied, or an error if the history diverges
+func (m *Migrator) Pending() ([]Migration, error) {
+	return m.pending(context.Background())
+}
+
+func (m *Migrator) pending(ctx context.Context) ([]Migration, error) {
+	for i, migration := range m.migrations {
+		if migration.Version <= 0 {
+			return nil, fmt.Errorf("Invalid migration version %d", migration.Version)
+		}
+		if i > 0 && m.migrations[i-1].Version == migration.Version {
+			return nil, fmt.Errorf("Duplicate migration version %d", migration.Version)
+		}
+		if (len(migration.CQL) == 0) == (migration.Func == nil) {
+			return nil, fmt.Errorf("Migration %d must have either CQL statements or a Func", migration.Version)
+		}
+	}
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(applied) > len(m.migrations) {
+		return nil, MigrationHistoryError{
+			Version: applied[len(m.migrations)].Version,
+			Reason:  "applied migration is unknown",
+		}
+	}
+	for i, a := range applied {
+		migration := m.migrations[i]
+		switch {
+		case migration.Version < a.Version:
+			return nil, MigrationHistoryError{Version: migration.Version, Reason: "migration is older than applied ones"}
+		case migration.Version > a.Version:
+			return nil, MigrationHistoryError{Version: a.Version, Reason: "applied migration is unknown"}
+		case migration.checksum() != a.Checksum:
+			return nil, MigrationHistoryError{Version: a.Version, Reason: "migration changed since it was applied"}
+		case a.AppliedAt.IsZero():
+			return nil, MigrationHistoryError{Version: a.Version, Reason: "migration is being applied, or its migrator stopped while applying it"}
+		}
+	}
+	return m.migrations[len(applied):], nil
+}
+
+// Migrate applies the pending migrations in order, stopping at the first one which fails
+func (m *Migrator) Migrate() error {
+	return m.MigrateContext(context.Background())
+}
+
+func (m *Migrator) MigrateContext(ctx context.Context) error {
+	if err := m.table.CreateIfNotExist(); err != nil {
+		return err
+	}
+	pending, err := m.pending(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range pending {
+		if err := m.claim(ctx, migration); err != nil {
+			return err
+		}
+		if err := m.apply(ctx, migration); err != nil {
+			// Release the claim, so that the migration can be retried
+			if relErr := m.record(migration).Delete().RunContext(ctx); relErr != nil {
+				return fmt.Errorf("%v, and releasing it failed: %v", err, relErr)
+			}
+			return err
+		}
+		err := m.record(migration).Update(map[string]interface{}{"applied_at": time.Now()}).RunContext(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) record(migration Migration) Filter {
+	return m.table.Where(Eq("scope", migrationScope), Eq("version", migration.Version))
+}
+
+// claim records the migration before it is applied, with a lightweight transaction so that migrators running
+// concurrently do not apply it twice. Its time of application is only recorded once it is applied.
+func (m *Migrator) claim(ctx context.Context, migration Migration) error {
+	result := CASResult{}
+	err := m.table.SetIfNotExists(AppliedMigration{
+		Scope:       migrationScope,
+		Version:     migration.Version,
+		Description: migration.Description,
+		Checksum:    migration.checksum(),
+	}, &result).RunContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !result.Applied {
+		return fmt.Errorf("Migration %d is being applied by another migrator", migration.Version)
+	}
+	return nil
+}
+
+func (m *Migrator) apply(ctx context.Context, migration Migration) error {
+	for _, stmt := range migration.CQL {
+		if err := m.ks.ExecuteContext(ctx, stmt); err != nil {
+			return fmt.Errorf("Migration %d failed: %v", migration.Version, err)
+		}
+	}
+	if migration.Func != nil {
+		if err := migration.Func(ctx, m.ks); err != nil {
+			return fmt.Errorf("Migration %d failed: %v", migration.Version, err)
+		}
+	}
+	return nil
+}
+
+// DryRun writes the pending migrations to w instead of applying them. It does not change the keyspace, not even to
+// create the history table.
+func (m *Migrator) DryRun(w io.Writer) error {
+	pending, err := m.pending(context.Background())
+	if err != nil {
+		return err
+	}
+	for _, migration := range pending {
+		fmt.Fprintf(w, "-- %d: %s\n", migration.Version, migration.Description)
+		for _, stmt := range migration.CQL {
+			fmt.Fprintf(w, "%s;\n", strings.TrimRight(strings.TrimSpace(stmt), ";"))
+		}
+		if migration.Func != nil {
+			fmt.Fprint(w, "-- (Go migration)\n")
+		}
+	}
+	return nil
+}
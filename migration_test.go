@@ -0,0 +1,176 @@
+package gocassa
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Mock QueryExecutor that keeps track of the statements executed through it
+type StatementRecordingQE struct {
+	OptionCheckingQE
+	stmts *[]string
+}
+
+func (qe StatementRecordingQE) ExecuteContext(ctx context.Context, opts Options, stmt string, params ...interface{}) error {
+	*qe.stmts = append(*qe.stmts, stmt)
+	return nil
+}
+
+func (qe StatementRecordingQE) Execute(stmt string, params ...interface{}) error {
+	return qe.ExecuteContext(context.Background(), Options{}, stmt, params...)
+}
+
+func testMigrations(calls *int) []Migration {
+	return []Migration{
+		{
+			Version:     2,
+			Description: "Backfill users",
+			Func: func(ctx context.Context, ks KeySpace) error {
+				*calls++
+				return nil
+			},
+		},
+		{
+			Version:     1,
+			Description: "Create users",
+			CQL:         []string{"CREATE TABLE users (id text PRIMARY KEY, name text)"},
+		},
+	}
+}
+
+func TestMigratorMock(t *testing.T) {
+	calls := 0
+	migrations := testMigrations(&calls)
+	ks := NewMockKeySpace()
+	m := NewMigrator(ks, migrations...)
+
+	buf := new(bytes.Buffer)
+	if err := m.DryRun(buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := "-- 1: Create users\nCREATE TABLE users (id text PRIMARY KEY, name text);\n-- 2: Backfill users\n-- (Go migration)\n"
+	if buf.String() != expected || calls != 0 {
+		t.Fatalf("Unexpected dry run %q", buf.String())
+	}
+	// Dry runs do not create the history table
+	if exists, err := ks.Exists(MigrationTableName); err != nil || exists {
+		t.Fatal("Expected the history table not to be created by a dry run", err)
+	}
+
+	// CQL migrations can not be applied to the mock keyspace, so they are not recorded either
+	if err := m.Migrate(); err == nil || !strings.HasPrefix(err.Error(), "Migration 1 failed: The mock keyspace can not execute CQL statements") {
+		t.Fatal(err)
+	}
+	if applied, err := m.Applied(); err != nil || len(applied) != 0 {
+		t.Fatal(applied, err)
+	}
+	m.migrations[0] = Migration{
+		Version:     1,
+		Description: "Create users",
+		Func: func(ctx context.Context, ks KeySpace) error {
+			return ks.Table("users", user{}, Keys{PartitionKeys: []string{"Pk1"}}).CreateIfNotExist()
+		},
+	}
+
+	if err := m.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	applied, err := m.Applied()
+	if err != nil || len(applied) != 2 || applied[0].Version != 1 || applied[1].Description != "Backfill users" || calls != 1 {
+		t.Fatal(applied, err)
+	}
+
+	// Applied migrations are not applied again
+	if err := m.Migrate(); err != nil || calls != 1 {
+		t.Fatal(err, calls)
+	}
+
+	// New migrations are
+	m.migrations = append(m.migrations, Migration{
+		Version: 3,
+		Func: func(ctx context.Context, ks KeySpace) error {
+			return nil
+		},
+	})
+	if pending, err := m.Pending(); err != nil || len(pending) != 1 || pending[0].Version != 3 {
+		t.Fatal(pending, err)
+	}
+	if err := m.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Migrations claimed by another migrator are not applied
+	m.migrations = append(m.migrations, Migration{
+		Version: 4,
+		Func: func(ctx context.Context, ks KeySpace) error {
+			calls++
+			return nil
+		},
+	})
+	if err := m.claim(context.Background(), m.migrations[3]); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.claim(context.Background(), m.migrations[3]); err == nil || err.Error() != "Migration 4 is being applied by another migrator" {
+		t.Fatal(err)
+	}
+	err = m.Migrate()
+	if _, ok := err.(MigrationHistoryError); !ok || calls != 1 {
+		t.Fatal(err, calls)
+	}
+	if err := m.record(m.migrations[3]).Delete().Run(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Migrate(); err != nil || calls != 2 {
+		t.Fatal(err, calls)
+	}
+
+	// The history must match the known migrations
+	table := m.table
+	for _, migrations := range [][]Migration{
+		m.migrations[:2],
+		append([]Migration{{Version: 1, CQL: []string{"CREATE TABLE users (id text PRIMARY KEY)"}}}, m.migrations[1:]...),
+		append(m.migrations[:1:1], m.migrations[2:]...),
+		append([]Migration{{Version: 1, Func: migrations[0].Func}}, m.migrations...),
+	} {
+		m := NewMigrator(ks, migrations...)
+		m.table = table
+		if err := m.Migrate(); err == nil {
+			t.Fatalf("Expected an error for %v", migrations)
+		}
+	}
+	m.migrations[1].Description = "Backfill all users"
+	err = m.Migrate()
+	if _, ok := err.(MigrationHistoryError); !ok || err.Error() != "Migration history diverges at version 2: migration changed since it was applied" {
+		t.Fatal(err)
+	}
+}
+
+func TestMigratorStatements(t *testing.T) {
+	stmts := []string{}
+	qe := StatementRecordingQE{OptionCheckingQE: OptionCheckingQE{opts: &Options{}}, stmts: &stmts}
+	ks := NewConnection(qe).KeySpace("ks")
+
+	calls := 0
+	migrations := testMigrations(&calls)
+	migrations = append(migrations, Migration{
+		Version: 3,
+		Func: func(ctx context.Context, ks KeySpace) error {
+			return errors.New("backfill failed")
+		},
+	})
+	err := NewMigrator(ks, migrations...).Migrate()
+	if err == nil || err.Error() != "Migration 3 failed: backfill failed" {
+		t.Fatal(err)
+	}
+
+	// Each migration is claimed with a lightweight transaction, and its claim is released when it fails
+	if len(stmts) != 5 || !strings.HasPrefix(stmts[0], "CREATE TABLE IF NOT EXISTS ks.schema_migrations") ||
+		stmts[1] != migrations[1].CQL[0] || !strings.HasPrefix(stmts[2], "UPDATE ks.schema_migrations") ||
+		!strings.HasPrefix(stmts[3], "UPDATE ks.schema_migrations") ||
+		!strings.HasPrefix(stmts[4], "DELETE FROM ks.schema_migrations") {
+		t.Fatal(stmts)
+	}
+}
@@ -2,18 +2,25 @@ package gocassa
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
-	"github.com/gocql/gocql"
+	r "github.com/gocassa/gocassa/reflect"
 	"github.com/google/btree"
 )
 
 // MockKeySpace implements the KeySpace interface and constructs in-memory tables.
 type mockKeySpace struct {
 	k
+	mtx    sync.RWMutex
+	tables map[string]*MockTable // The tables which were created, by lower case name
 }
 
 type mockOp struct {
@@ -33,7 +40,14 @@ func (m mockOp) Add(ops ...Op) Op {
 }
 
 func (m mockOp) Run() error {
+	return m.RunContext(context.Background())
+}
+
+func (m mockOp) RunContext(ctx context.Context) error {
 	for _, f := range m.funcs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		err := f(m)
 		if err != nil {
 			return err
@@ -42,10 +56,13 @@ func (m mockOp) Run() error {
 	return nil
 }
 
+// WithOptions merges the options into the ones of the op, as singleOp does, so that options set on an op are kept
+// when the options of a recipe are applied to it
 func (m mockOp) WithOptions(opt Options) Op {
 	return mockOp{
-		options: opt,
-		funcs:   m.funcs,
+		options:      m.options.Merge(opt),
+		funcs:        m.funcs,
+		preflightErr: m.preflightErr,
 	}
 }
 
@@ -53,6 +70,26 @@ func (m mockOp) RunAtomically() error {
 	return m.Run()
 }
 
+func (m mockOp) RunAtomicallyContext(ctx context.Context) error {
+	return m.RunContext(ctx)
+}
+
+func (m mockOp) RunBatch(batchType BatchType) error {
+	return m.Run()
+}
+
+func (m mockOp) RunBatchContext(ctx context.Context, batchType BatchType) error {
+	return m.RunContext(ctx)
+}
+
+func (m mockOp) RunParallel(concurrency int) error {
+	return m.Run()
+}
+
+func (m mockOp) RunParallelContext(ctx context.Context, concurrency int) error {
+	return m.RunContext(ctx)
+}
+
 func (m mockOp) GenerateStatement() (string, []interface{}) {
 	return "", []interface{}{}
 }
@@ -67,66 +104,281 @@ func (m mockOp) Preflight() error {
 
 func (ks *mockKeySpace) NewTable(name string, entity interface{}, fields map[string]interface{}, keys Keys) Table {
 	return &MockTable{
-		name:   name,
-		entity: entity,
-		keys:   keys,
-		rows:   map[rowKey]*btree.BTree{},
+		RWMutex:      &sync.RWMutex{},
+		mtx:          &sync.RWMutex{},
+		ks:           ks,
+		name:         name,
+		entity:       entity,
+		fields:       fields,
+		fieldOptions: fieldOptions(entity),
+		keys:         keys,
+		rows:         map[rowKey]*btree.BTree{},
+		statics:      map[rowKey]*superColumn{},
+	}
+}
+
+func (ks *mockKeySpace) WithQueryObserver(observer QueryObserver) KeySpace {
+	return ks
+}
+
+func (ks *mockKeySpace) Tables() ([]string, error) {
+	ks.mtx.RLock()
+	defer ks.mtx.RUnlock()
+	ret := []string{}
+	for name := range ks.tables {
+		ret = append(ret, name)
 	}
+	sort.Strings(ret)
+	return ret, nil
+}
+
+func (ks *mockKeySpace) Exists(cf string) (bool, error) {
+	ks.mtx.RLock()
+	defer ks.mtx.RUnlock()
+	_, ok := ks.tables[strings.ToLower(cf)]
+	return ok, nil
+}
+
+func (ks *mockKeySpace) TableSchema(name string) (*TableSchema, error) {
+	ks.mtx.RLock()
+	t, ok := ks.tables[strings.ToLower(name)]
+	ks.mtx.RUnlock()
+	if !ok {
+		return nil, nil
+	}
+	fields, values := keyValues(t.fields)
+	types := make([]string, len(fields))
+	for i, field := range fields {
+		typ, err := columnTypeOf(values[i], t.fieldOptions[strings.ToLower(field)])
+		if err != nil {
+			return nil, err
+		}
+		types[i] = typ
+	}
+	return structSchema(name, t.keys, fields, types, t.options.ClusteringOrder), nil
+}
+
+// ExecuteContext fails, as the mock keyspace does not interpret CQL statements
+func (ks *mockKeySpace) ExecuteContext(ctx context.Context, stmt string, params ...interface{}) error {
+	return fmt.Errorf("The mock keyspace can not execute CQL statements: %s", stmt)
+}
+
+func (ks *mockKeySpace) CreateType(row interface{}) error {
+	_, err := ks.CreateTypeStatements(row)
+	return err
+}
+
+// DropTable forgets about the table, its rows are kept by the tables it was obtained through
+func (ks *mockKeySpace) DropTable(cf string) error {
+	ks.mtx.Lock()
+	defer ks.mtx.Unlock()
+	delete(ks.tables, strings.ToLower(cf))
+	return nil
+}
+
+func (ks *mockKeySpace) create(t *MockTable) {
+	ks.mtx.Lock()
+	defer ks.mtx.Unlock()
+	ks.tables[strings.ToLower(t.Name())] = t
 }
 
 func NewMockKeySpace() KeySpace {
-	ks := &mockKeySpace{}
+	ks := &mockKeySpace{tables: map[string]*MockTable{}}
 	ks.tableFactory = ks
 	return ks
 }
 
 // MockTable implements the Table interface and stores rows in-memory.
 type MockTable struct {
-	sync.RWMutex
+	// The locks are shared by all the copies of a table (see WithOptions) as they share the rows
+	*sync.RWMutex
 
 	// rows is mapping from row key to column group key to column map
-	mtx     sync.RWMutex
+	mtx     *sync.RWMutex
+	ks      *mockKeySpace
 	name    string
 	rows    map[rowKey]*btree.BTree
+	// statics holds the static columns of each partition
+	statics map[rowKey]*superColumn
 	entity  interface{}
+	fields  map[string]interface{}
 	keys    Keys
 	options Options
+	// fieldOptions holds the tag options of the fields, by lower cased field name
+	fieldOptions map[string][]string
 }
 
 type rowKey string
 type superColumn struct {
 	Key     key
 	Columns map[string]interface{}
+	// Timestamps holds the write time of each column in microseconds
+	Timestamps map[string]int64
+	// Expiries holds the time columns written with a TTL expire at
+	Expiries map[string]time.Time
 }
 
-func (c *superColumn) Less(item btree.Item) bool {
-	other, ok := item.(*superColumn)
-	if !ok {
-		return false
+// set writes a column unless it holds a value written later (last write wins)
+func (c *superColumn) set(column string, value interface{}, timestamp int64, ttl time.Duration) {
+	if ts, ok := c.Timestamps[column]; ok && ts > timestamp {
+		return
+	}
+	c.Columns[column] = udtValue(value)
+	c.Timestamps[column] = timestamp
+	if ttl > 0 {
+		c.Expiries[column] = mockNow().Add(ttl)
+	} else {
+		delete(c.Expiries, column)
 	}
+}
 
-	return c.Key.Less(other.Key)
+// current returns the value of a column, or nil if it does not have one
+func (c *superColumn) current(column string) interface{} {
+	if exp, ok := c.Expiries[column]; ok && !mockNow().Before(exp) {
+		return nil
+	}
+	return c.Columns[column]
+}
+
+// touch writes a key column, unless it already lives longer than the write would make it live. Updates use it so
+// that they do not shorten the life of a row inserted without a TTL. The write time of the keys is the one of the
+// latest write, as it is for the liveness of rows in Cassandra, so that the row outlives older deletes.
+func (c *superColumn) touch(column string, value interface{}, timestamp int64, ttl time.Duration) {
+	if _, ok := c.Columns[column]; ok {
+		exp, expires := c.Expiries[column]
+		if !expires || (ttl > 0 && exp.After(mockNow().Add(ttl))) {
+			if c.Timestamps[column] < timestamp {
+				c.Timestamps[column] = timestamp
+			}
+			return
+		}
+	}
+	c.set(column, value, timestamp, ttl)
+}
+
+// view returns a copy of the columns which have not expired by now, along with the requested WRITETIME() and TTL()
+// selectors, or nil if every column has expired
+func (c *superColumn) view(now time.Time, metadata []string) map[string]interface{} {
+	columns := map[string]interface{}{}
+	for column, value := range c.Columns {
+		if exp, ok := c.Expiries[column]; ok && !now.Before(exp) {
+			continue
+		}
+		columns[column] = value
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+
+	for _, selector := range metadata {
+		fn, name, ok := parseMetadataSelector(selector)
+		if !ok {
+			continue
+		}
+		for column := range columns {
+			if !strings.EqualFold(column, name) {
+				continue
+			}
+			switch fn {
+			case "writetime":
+				columns[strings.ToLower(selector)] = c.Timestamps[column]
+			case "ttl":
+				if exp, ok := c.Expiries[column]; ok {
+					columns[strings.ToLower(selector)] = int((exp.Sub(now) + time.Second - 1) / time.Second)
+				}
+			}
+		}
+	}
+	return columns
 }
 
-type gocqlTypeInfo struct {
-	proto byte
-	typ   gocql.Type
+// parseMetadataSelector splits selectors like "WRITETIME(name)" into the lowercase function and the column name
+func parseMetadataSelector(selector string) (string, string, bool) {
+	open := strings.Index(selector, "(")
+	if open < 0 || !strings.HasSuffix(selector, ")") {
+		return "", "", false
+	}
+	fn := strings.ToLower(strings.TrimSpace(selector[:open]))
+	if fn != "writetime" && fn != "ttl" {
+		return "", "", false
+	}
+	return fn, strings.TrimSpace(selector[open+1 : len(selector)-1]), true
+}
+
+// deleteBefore deletes the columns written at or before the timestamp, returning whether the row survived, ie. it
+// was written after the timestamp or any column other than the keys survived. The keys themselves are left alone.
+func (c *superColumn) deleteBefore(timestamp int64, keys Keys) bool {
+	alive := false
+	for column, ts := range c.Timestamps {
+		if containsString(keys.PartitionKeys, column) || containsString(keys.ClusteringColumns, column) {
+			alive = alive || ts > timestamp
+			continue
+		}
+		if ts <= timestamp {
+			delete(c.Columns, column)
+			delete(c.Timestamps, column)
+			delete(c.Expiries, column)
+		}
+	}
+	for column := range c.Columns {
+		if !containsString(keys.PartitionKeys, column) && !containsString(keys.ClusteringColumns, column) {
+			return true
+		}
+	}
+	return alive
+}
+
+var (
+	mockClockMtx sync.RWMutex
+	mockClock    = time.Now
+)
+
+// mockNow reads the clock of the mock, used for write times and expiring columns written with a TTL
+func mockNow() time.Time {
+	mockClockMtx.RLock()
+	defer mockClockMtx.RUnlock()
+	return mockClock()
 }
 
-func (t gocqlTypeInfo) New() interface{} {
-	return &gocqlTypeInfo{t.proto, t.typ}
+// SetMockClock replaces the clock of the mock keyspaces, which lets tests expire the columns written with a TTL, eg.
+// leases, without waiting. It returns a function restoring the previous clock. The clock is shared by all the mock
+// keyspaces, so tests setting it should not run in parallel with other tests using the mock.
+func SetMockClock(now func() time.Time) (restore func()) {
+	mockClockMtx.Lock()
+	defer mockClockMtx.Unlock()
+	previous := mockClock
+	mockClock = now
+	return func() {
+		mockClockMtx.Lock()
+		defer mockClockMtx.Unlock()
+		mockClock = previous
+	}
 }
 
-func (t gocqlTypeInfo) Type() gocql.Type {
-	return t.typ
+// mockTTL returns the TTL of the columns written with the given options, which is the default TTL of the table
+// unless the op sets one
+func mockTTL(opts Options) time.Duration {
+	if opts.TTL == 0 && opts.Properties != nil {
+		return opts.Properties.DefaultTTL
+	}
+	return opts.TTL
 }
 
-func (t gocqlTypeInfo) Version() byte {
-	return t.proto
+// mockTimestamp returns the write time to use for the given options in microseconds
+func mockTimestamp(opts Options) int64 {
+	if opts.Timestamp.IsZero() {
+		return mockNow().UnixNano() / int64(time.Microsecond)
+	}
+	return opts.Timestamp.UnixNano() / int64(time.Microsecond)
 }
 
-func (t gocqlTypeInfo) Custom() string {
-	return ""
+func (c *superColumn) Less(item btree.Item) bool {
+	other, ok := item.(*superColumn)
+	if !ok {
+		return false
+	}
+
+	return c.Key.Less(other.Key)
 }
 
 type keyPart struct {
@@ -135,11 +387,7 @@ type keyPart struct {
 }
 
 func (k *keyPart) Bytes() []byte {
-	typeInfo := &gocqlTypeInfo{
-		proto: 0x03,
-		typ:   cassaType(k.Value),
-	}
-	marshalled, err := gocql.Marshal(typeInfo, k.Value)
+	marshalled, err := marshalValue(cassaType(k.Value), k.Value)
 	if err != nil {
 		panic(err)
 	}
@@ -218,17 +466,83 @@ func (t *MockTable) getOrCreateRow(rowKey key) *btree.BTree {
 	return row
 }
 
-func (t *MockTable) getOrCreateColumnGroup(rowKey, superColumnKey key) map[string]interface{} {
+func (t *MockTable) getOrCreateColumnGroup(rowKey, superColumnKey key) *superColumn {
 	row := t.getOrCreateRow(rowKey)
 	scol := superColumnKey.ToSuperColumn()
 
 	if row.Has(scol) {
-		return row.Get(scol).(*superColumn).Columns
+		return row.Get(scol).(*superColumn)
 	}
 	row.ReplaceOrInsert(scol)
 	scol.Columns = map[string]interface{}{}
+	scol.Timestamps = map[string]int64{}
+	scol.Expiries = map[string]time.Time{}
+
+	return scol
+}
+
+// columnGroupFor returns the column group a column of the given column group is written to, which is the one shared
+// by the partition for static columns
+func (t *MockTable) columnGroupFor(column string, rowKey key, scol *superColumn) *superColumn {
+	if !containsString(t.keys.StaticColumns, column) {
+		return scol
+	}
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	statics := t.statics[rowKey.RowKey()]
+	if statics == nil {
+		statics = &superColumn{
+			Columns:    map[string]interface{}{},
+			Timestamps: map[string]int64{},
+			Expiries:   map[string]time.Time{},
+		}
+		t.statics[rowKey.RowKey()] = statics
+	}
+	return statics
+}
 
-	return scol.Columns
+// withStatics adds the static columns of the partition to the columns of one of its rows. It expects mtx to be held.
+func (t *MockTable) withStatics(rowKey key, columns map[string]interface{}, now time.Time, metadata []string) map[string]interface{} {
+	statics := t.statics[rowKey.RowKey()]
+	if columns == nil || statics == nil {
+		return columns
+	}
+	for k, v := range statics.view(now, metadata) {
+		columns[k] = v
+	}
+	return columns
+}
+
+// getColumnGroup returns a copy of the live columns stored under the given keys, or nil if there are none
+func (t *MockTable) getColumnGroup(rowKey, superColumnKey key) map[string]interface{} {
+	t.mtx.RLock()
+	defer t.mtx.RUnlock()
+	row := t.rows[rowKey.RowKey()]
+	if row == nil {
+		return nil
+	}
+	item := row.Get(superColumnKey.ToSuperColumn())
+	if item == nil {
+		return nil
+	}
+
+	now := mockNow()
+	return t.withStatics(rowKey, item.(*superColumn).view(now, nil), now, nil)
+}
+
+// metadata returns the WRITETIME() and TTL() selectors a read with the given options returns: the ones of the
+// entity's metadata fields, or the ones in Options.Select
+func (t *MockTable) metadata(opts Options) []string {
+	if len(opts.Select) == 0 {
+		return r.MetadataFields(t.entity)
+	}
+	metadata := []string{}
+	for _, selector := range opts.Select {
+		if _, _, ok := parseMetadataSelector(selector); ok {
+			metadata = append(metadata, selector)
+		}
+	}
+	return metadata
 }
 
 func (t *MockTable) SetWithOptions(i interface{}, options Options) Op {
@@ -252,18 +566,72 @@ func (t *MockTable) SetWithOptions(i interface{}, options Options) Op {
 		}
 
 		superColumn := t.getOrCreateColumnGroup(rowKey, superColumnKey)
+		opts := options.Merge(m.options)
+		timestamp := mockTimestamp(opts)
 
 		for k, v := range columns {
-			superColumn[k] = v
+			scol := t.columnGroupFor(k, rowKey, superColumn)
+			if c, ok := v.(Counter); ok {
+				// Counters are incremented rather than overwritten, see transformFields
+				v = mockIncrement(scol.current(k), int(c))
+			}
+			scol.set(k, t.columnValue(k, v), timestamp, mockTTL(opts))
 		}
 		return nil
 	})
 }
 
+// columnValue returns the value stored in the column of the field, see columnValue. Sets are sorted and deduplicated.
+func (t *MockTable) columnValue(field string, value interface{}) interface{} {
+	options := t.fieldOptions[strings.ToLower(field)]
+	value = columnValue(value, options)
+	if containsString(options, setOption) {
+		value = setValue(value)
+	}
+	return value
+}
+
 func (t *MockTable) Set(i interface{}) Op {
 	return t.SetWithOptions(i, t.options)
 }
 
+func (t *MockTable) SetIfNotExists(i interface{}, result *CASResult) Op {
+	cas := &casCondition{notExists: true, result: result}
+	return newOp(func(m mockOp) error {
+		t.Lock()
+		defer t.Unlock()
+
+		columns, ok := toMap(i)
+		if !ok {
+			return errors.New("Can't create: value not understood")
+		}
+
+		rowKey, err := t.keyFromColumnValues(columns, t.keys.PartitionKeys)
+		if err != nil {
+			return err
+		}
+
+		superColumnKey, err := t.keyFromColumnValues(columns, t.keys.ClusteringColumns)
+		if err != nil {
+			return err
+		}
+
+		if current := t.getColumnGroup(rowKey, superColumnKey); current != nil {
+			cas.setResult(false, current)
+			return nil
+		}
+		cas.setResult(true, nil)
+
+		superColumn := t.getOrCreateColumnGroup(rowKey, superColumnKey)
+		opts := t.options.Merge(m.options)
+		timestamp := mockTimestamp(opts)
+		for k, v := range columns {
+			t.columnGroupFor(k, rowKey, superColumn).set(k, t.columnValue(k, v), timestamp, mockTTL(opts))
+		}
+		return nil
+	})
+}
+
 func (t *MockTable) Where(relations ...Relation) Filter {
 	return &MockFilter{
 		table:     t,
@@ -272,6 +640,7 @@ func (t *MockTable) Where(relations ...Relation) Filter {
 }
 
 func (t *MockTable) Create() error {
+	t.ks.create(t)
 	return nil
 }
 
@@ -280,6 +649,7 @@ func (t *MockTable) CreateStatement() (string, error) {
 }
 
 func (t *MockTable) CreateIfNotExist() error {
+	t.ks.create(t)
 	return nil
 }
 
@@ -288,16 +658,47 @@ func (t *MockTable) CreateIfNotExistStatement() (string, error) {
 }
 
 func (t *MockTable) Recreate() error {
+	t.ks.create(t)
+	return nil
+}
+
+func (t *MockTable) SyncStatements() ([]string, error) {
+	return nil, nil
+}
+
+func (t *MockTable) Sync() error {
+	t.ks.create(t)
+	return nil
+}
+
+func (t *MockTable) AlterStatement() (string, error) {
+	return alterTablePropertiesStmt(t.ks.name, t.Name(), t.options.tableProperties())
+}
+
+func (t *MockTable) Alter() error {
+	if _, err := t.AlterStatement(); err != nil {
+		return err
+	}
+	if ok, _ := t.ks.Exists(t.Name()); !ok {
+		return fmt.Errorf("Table %s does not exist", t.Name())
+	}
+	t.ks.create(t)
 	return nil
 }
 
 func (t *MockTable) WithOptions(o Options) Table {
 	return &MockTable{
-		name:    t.name,
-		rows:    t.rows,
-		entity:  t.entity,
-		keys:    t.keys,
-		options: t.options.Merge(o),
+		RWMutex:      t.RWMutex,
+		mtx:          t.mtx,
+		ks:           t.ks,
+		name:         t.name,
+		fields:       t.fields,
+		fieldOptions: t.fieldOptions,
+		rows:         t.rows,
+		statics:      t.statics,
+		entity:       t.entity,
+		keys:         t.keys,
+		options:      t.options.Merge(o),
 	}
 }
 
@@ -389,34 +790,140 @@ func (f *MockFilter) UpdateWithOptions(m map[string]interface{}, options Options
 		f.table.Lock()
 		defer f.table.Unlock()
 
-		rowKeys, err := f.keysFromRelations(f.table.keys.PartitionKeys)
+		return f.update(m, f.table.options.Merge(options).Merge(mock.options))
+	})
+}
+
+// update expects the table lock to be held
+func (f *MockFilter) update(m map[string]interface{}, opts Options) error {
+	timestamp := mockTimestamp(opts)
+	rowKeys, err := f.keysFromRelations(f.table.keys.PartitionKeys)
+	if err != nil {
+		return err
+	}
+
+	for _, rowKey := range rowKeys {
+		superColumnKeys, err := f.keysFromRelations(f.table.keys.ClusteringColumns)
 		if err != nil {
 			return err
 		}
 
-		for _, rowKey := range rowKeys {
-			superColumnKeys, err := f.keysFromRelations(f.table.keys.ClusteringColumns)
-			if err != nil {
-				return err
-			}
+		for _, superColumnKey := range superColumnKeys {
+			superColumn := f.table.getOrCreateColumnGroup(rowKey, superColumnKey)
 
-			for _, superColumnKey := range superColumnKeys {
-				superColumn := f.table.getOrCreateColumnGroup(rowKey, superColumnKey)
+			for _, key := range []key{rowKey, superColumnKey} {
+				for _, keyPart := range key {
+					superColumn.touch(keyPart.Key, keyPart.Value, timestamp, mockTTL(opts))
+				}
+			}
 
-				for _, key := range []key{rowKey, superColumnKey} {
-					for _, keyPart := range key {
-						superColumn[keyPart.Key] = keyPart.Value
+			for key, value := range m {
+				scol := f.table.columnGroupFor(key, rowKey, superColumn)
+				if mod, ok := value.(Modifier); ok {
+					if value, err = applyModifier(mod, scol.current(key)); err != nil {
+						return err
 					}
 				}
+				scol.set(key, f.table.columnValue(key, value), timestamp, mockTTL(opts))
+			}
+		}
+	}
 
-				for key, value := range m {
-					superColumn[key] = value
-				}
+	return nil
+}
+
+// applyModifier returns the value of a column once the modifier is applied to its current value. Lists and sets are
+// returned as []interface{} and maps as map[interface{}]interface{}, their elements are decoded when read.
+func applyModifier(mod Modifier, current interface{}) (interface{}, error) {
+	switch mod.op {
+	case modifierListPrepend:
+		return append([]interface{}{mod.args[0]}, mockList(current)...), nil
+	case modifierListAppend, modifierSetAdd:
+		return append(mockList(current), mod.args[0]), nil
+	case modifierListSetAtIndex:
+		list, index := mockList(current), mod.args[0].(int)
+		if index < 0 || index >= len(list) {
+			return nil, fmt.Errorf("List index %d out of bound, list size %d", index, len(list))
+		}
+		list[index] = mod.args[1]
+		return list, nil
+	case modifierListRemove, modifierSetRemove:
+		list := []interface{}{}
+		for _, v := range mockList(current) {
+			if !mockEqual(v, mod.args[0]) {
+				list = append(list, v)
 			}
 		}
+		return list, nil
+	case modifierMapSetFields:
+		fields, ok := mod.args[0].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("Argument for MapSetFields is not a map: %v", mod.args[0])
+		}
+		m := mockMap(current)
+		for k, v := range fields {
+			m[k] = v
+		}
+		return m, nil
+	case modifierMapSetField:
+		m := mockMap(current)
+		m[mod.args[0]] = mod.args[1]
+		return m, nil
+	case modifierCounterIncrement:
+		return mockIncrement(current, mod.args[0].(int)), nil
+	}
+	return nil, fmt.Errorf("Modifier %d is not supported by the mock keyspace", mod.op)
+}
+
+// mockIncrement returns the value of a counter once incremented by delta. Counters are read as int64, like gocql
+// reads them, and missing counters count as zero.
+func mockIncrement(current interface{}, delta int) interface{} {
+	v := reflect.ValueOf(current)
+	if !v.IsValid() || !isNumeric(v.Kind()) {
+		return int64(delta)
+	}
+	return v.Convert(reflect.TypeOf(int64(0))).Int() + int64(delta)
+}
+
+// mockList returns a copy of the elements of a list or set column
+func mockList(current interface{}) []interface{} {
+	list := []interface{}{}
+	v := reflect.ValueOf(current)
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		for i := 0; i < v.Len(); i++ {
+			list = append(list, v.Index(i).Interface())
+		}
+	}
+	return list
+}
 
-		return nil
-	})
+// mockEqual compares an element of a column with the argument of a modifier, which Cassandra would have converted
+// into the type of the column, eg. ListRemove(2) removes 2 from a list<bigint>
+func mockEqual(elem, arg interface{}) bool {
+	if reflect.DeepEqual(elem, arg) {
+		return true
+	}
+	ev, av := reflect.ValueOf(elem), reflect.ValueOf(arg)
+	if !isNumeric(ev.Kind()) || !isNumeric(av.Kind()) {
+		return false
+	}
+	return av.Convert(ev.Type()).Interface() == elem
+}
+
+func isNumeric(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Float64
+}
+
+// mockMap returns a copy of the entries of a map column
+func mockMap(current interface{}) map[interface{}]interface{} {
+	m := map[interface{}]interface{}{}
+	v := reflect.ValueOf(current)
+	if v.Kind() == reflect.Map {
+		for _, k := range v.MapKeys() {
+			m[k.Interface()] = v.MapIndex(k).Interface()
+		}
+	}
+	return m
 }
 
 func (f *MockFilter) Update(m map[string]interface{}) Op {
@@ -428,38 +935,119 @@ func (f *MockFilter) Delete() Op {
 		f.table.Lock()
 		defer f.table.Unlock()
 
-		rowKeys, err := f.keysFromRelations(f.table.keys.PartitionKeys)
-		if err != nil {
-			return err
-		}
+		return f.delete(mockTimestamp(f.table.options.Merge(m.options)))
+	})
+}
 
-		f.table.mtx.Lock()
-		defer f.table.mtx.Unlock()
-		for _, rowKey := range rowKeys {
-			row := f.table.rows[rowKey.RowKey()]
-			if row == nil {
-				return nil
+// delete removes the columns written at or before the timestamp from the matching rows, and the rows themselves if
+// nothing but their keys is left. It expects the table lock to be held.
+func (f *MockFilter) delete(timestamp int64) error {
+	rowKeys, err := f.keysFromRelations(f.table.keys.PartitionKeys)
+	if err != nil {
+		return err
+	}
+
+	// Deleting whole partitions deletes their static columns as well
+	partition := true
+	for _, relation := range f.relations {
+		partition = partition && containsString(f.table.keys.PartitionKeys, relation.key)
+	}
+
+	f.table.mtx.Lock()
+	defer f.table.mtx.Unlock()
+	for _, rowKey := range rowKeys {
+		if statics := f.table.statics[rowKey.RowKey()]; partition && statics != nil {
+			if !statics.deleteBefore(timestamp, Keys{}) {
+				delete(f.table.statics, rowKey.RowKey())
 			}
-			
-			targets := []btree.Item{}
+		}
 
-			row.Ascend(func(item btree.Item) bool {
-				columns := item.(*superColumn).Columns
-				if f.rowMatch(columns) {
-					targets = append(targets, row.Get(item))
-				}
+		row := f.table.rows[rowKey.RowKey()]
+		if row == nil {
+			continue
+		}
 
-				return true
-			})
-			for _, item := range targets {
+		targets := []btree.Item{}
+		now := mockNow()
+
+		row.Ascend(func(item btree.Item) bool {
+			columns := item.(*superColumn).view(now, nil)
+			if columns != nil && f.rowMatch(columns) {
+				targets = append(targets, row.Get(item))
+			}
+
+			return true
+		})
+		for _, item := range targets {
+			if !item.(*superColumn).deleteBefore(timestamp, f.table.keys) {
 				row.Delete(item)
 			}
 		}
+	}
 
-		return nil
+	return nil
+}
+
+func (f *MockFilter) UpdateIf(conditions []Relation, m map[string]interface{}, result *CASResult) Op {
+	cas := &casCondition{conditions: conditions, result: result}
+	return newOp(func(mock mockOp) error {
+		f.table.Lock()
+		defer f.table.Unlock()
+
+		if applied, err := f.compare(cas); !applied || err != nil {
+			return err
+		}
+		return f.update(m, f.table.options.Merge(mock.options))
 	})
 }
 
+func (f *MockFilter) DeleteIf(conditions []Relation, result *CASResult) Op {
+	cas := &casCondition{conditions: conditions, result: result}
+	return newOp(func(mock mockOp) error {
+		f.table.Lock()
+		defer f.table.Unlock()
+
+		if applied, err := f.compare(cas); !applied || err != nil {
+			return err
+		}
+		return f.delete(mockTimestamp(f.table.options.Merge(mock.options)))
+	})
+}
+
+// compare evaluates the condition of a lightweight transaction against the single row selected by the filter,
+// and fills in the result. It expects the table lock to be held.
+func (f *MockFilter) compare(cas *casCondition) (bool, error) {
+	rowKeys, err := f.keysFromRelations(f.table.keys.PartitionKeys)
+	if err != nil {
+		return false, err
+	}
+	superColumnKeys, err := f.keysFromRelations(f.table.keys.ClusteringColumns)
+	if err != nil {
+		return false, err
+	}
+	if len(rowKeys) != 1 || len(superColumnKeys) != 1 {
+		return false, errors.New("Conditional writes must select a single row")
+	}
+
+	columns := f.table.getColumnGroup(rowKeys[0], superColumnKeys[0])
+	if columns == nil {
+		cas.setResult(false, nil)
+		return false, nil
+	}
+
+	current := map[string]interface{}{}
+	applied := true
+	for _, condition := range cas.conditions {
+		value := columns[condition.key]
+		current[condition.key] = value
+		if !condition.accept(value) {
+			applied = false
+		}
+	}
+	cas.setResult(applied, current)
+	return applied, nil
+}
+
 func (q *MockFilter) Read(out interface{}) Op {
 	return newOp(func(m mockOp) error {
 		q.table.Lock()
@@ -472,6 +1060,8 @@ func (q *MockFilter) Read(out interface{}) Op {
 
 		q.table.mtx.RLock()
 		defer q.table.mtx.RUnlock()
+		opt := q.table.options.Merge(m.options)
+		now, metadata := mockNow(), q.table.metadata(opt)
 		var result []map[string]interface{}
 		for _, rowKey := range rowKeys {
 			row := q.table.rows[rowKey.RowKey()]
@@ -479,24 +1069,221 @@ func (q *MockFilter) Read(out interface{}) Op {
 				continue
 			}
 
+			var partition []map[string]interface{}
 			row.Ascend(func(item btree.Item) bool {
-				columns := item.(*superColumn).Columns
-				if q.rowMatch(columns) {
-					result = append(result, columns)
+				columns := q.table.withStatics(rowKey, item.(*superColumn).view(now, metadata), now, metadata)
+				if columns != nil && q.rowMatch(columns) {
+					partition = append(partition, columns)
 				}
 
 				return true
 			})
+			result = append(result, q.table.clusteringOrder(partition, opt.ClusteringOrder)...)
 		}
-		opt := q.table.options.Merge(m.options)
 		if opt.Limit > 0 && opt.Limit < len(result) {
 			result = result[:opt.Limit]
 		}
+		if opt.Paging != nil {
+			result, err = mockPage(opt.Paging, result)
+			if err != nil {
+				return err
+			}
+		}
 
 		return q.assignResult(result, out)
 	})
 }
 
+// clusteringOrder sorts the rows of a partition, which are stored in ascending order, in the clustering order of
+// the table
+func (t *MockTable) clusteringOrder(rows []map[string]interface{}, order []ClusteringOrderColumn) []map[string]interface{} {
+	desc := map[string]bool{}
+	for _, o := range order {
+		for _, c := range t.keys.ClusteringColumns {
+			if strings.EqualFold(o.Column, c) && o.Direction == DESC {
+				desc[c] = true
+			}
+		}
+	}
+	if len(desc) == 0 {
+		return rows
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		for _, c := range t.keys.ClusteringColumns {
+			a, b := rows[i][c], rows[j][c]
+			if desc[c] {
+				a, b = b, a
+			}
+			if less, err := builtinLessThan(a, b); err == nil && less {
+				return true
+			}
+			if less, err := builtinLessThan(b, a); err == nil && less {
+				return false
+			}
+		}
+		return false
+	})
+	return rows
+}
+
+func (q *MockFilter) Iter() Iterator {
+	return q.IterContext(context.Background())
+}
+
+func (q *MockFilter) IterContext(ctx context.Context) Iterator {
+	rowKeys, err := q.keysFromRelations(q.table.keys.PartitionKeys)
+	if err != nil {
+		return &badIterator{err}
+	}
+
+	descending := false
+	for _, o := range q.table.options.ClusteringOrder {
+		descending = descending || o.Direction == DESC
+	}
+	return newIterator(&mockRowIterator{
+		ctx:        ctx,
+		filter:     q,
+		rowKeys:    rowKeys,
+		metadata:   q.table.metadata(q.table.options),
+		limit:      q.table.options.Limit,
+		descending: descending,
+	})
+}
+
+// mockRowIterator ascends the btrees of the matching rows lazily, one super column at a time. The btrees are in
+// ascending order, so if the table has a descending clustering order the matching rows of each partition are read
+// and sorted at once instead.
+type mockRowIterator struct {
+	ctx        context.Context
+	filter     *MockFilter
+	rowKeys    []key
+	metadata   []string
+	last       *superColumn
+	limit      int
+	count      int
+	err        error
+	descending bool
+	sorted     []map[string]interface{}
+}
+
+// scanSorted fills the map with the next row of the sorted partitions, it expects the table locks to be held
+func (it *mockRowIterator) scanSorted(m map[string]interface{}, now time.Time) bool {
+	t := it.filter.table
+	for len(it.rowKeys) > 0 {
+		if it.sorted == nil {
+			it.sorted = []map[string]interface{}{}
+			if row := t.rows[it.rowKeys[0].RowKey()]; row != nil {
+				row.Ascend(func(item btree.Item) bool {
+					columns := t.withStatics(it.rowKeys[0], item.(*superColumn).view(now, it.metadata), now, it.metadata)
+					if columns != nil && it.filter.rowMatch(columns) {
+						it.sorted = append(it.sorted, columns)
+					}
+					return true
+				})
+			}
+			it.sorted = t.clusteringOrder(it.sorted, t.options.ClusteringOrder)
+		}
+
+		if len(it.sorted) > 0 {
+			it.count++
+			for k, v := range it.sorted[0] {
+				m[k] = v
+			}
+			it.sorted = it.sorted[1:]
+			return true
+		}
+		it.rowKeys = it.rowKeys[1:]
+		it.sorted = nil
+	}
+	return false
+}
+
+func (it *mockRowIterator) MapScan(m map[string]interface{}) bool {
+	if it.err != nil {
+		return false
+	}
+	if err := it.ctx.Err(); err != nil {
+		it.err = err
+		return false
+	}
+	if it.limit > 0 && it.count >= it.limit {
+		return false
+	}
+
+	t := it.filter.table
+	t.RLock()
+	defer t.RUnlock()
+	t.mtx.RLock()
+	defer t.mtx.RUnlock()
+
+	now := mockNow()
+	if it.descending {
+		return it.scanSorted(m, now)
+	}
+	for len(it.rowKeys) > 0 {
+		var next *superColumn
+		var columns map[string]interface{}
+		if row := t.rows[it.rowKeys[0].RowKey()]; row != nil {
+			visit := func(item btree.Item) bool {
+				scol := item.(*superColumn)
+				if it.last != nil && !it.last.Less(scol) {
+					return true
+				}
+				columns = t.withStatics(it.rowKeys[0], scol.view(now, it.metadata), now, it.metadata)
+				if columns != nil && it.filter.rowMatch(columns) {
+					next = scol
+					return false
+				}
+				return true
+			}
+			if it.last == nil {
+				row.Ascend(visit)
+			} else {
+				row.AscendGreaterOrEqual(it.last, visit)
+			}
+		}
+
+		if next != nil {
+			it.last = next
+			it.count++
+			for k, v := range columns {
+				m[k] = v
+			}
+			return true
+		}
+		it.rowKeys = it.rowKeys[1:]
+		it.last = nil
+	}
+	return false
+}
+
+func (it *mockRowIterator) Close() error {
+	return it.err
+}
+
+// mockPage emulates paging by encoding the offset of the next page in the paging state
+func mockPage(p *Paging, result []map[string]interface{}) ([]map[string]interface{}, error) {
+	offset := 0
+	if len(p.State) > 0 {
+		var err error
+		offset, err = strconv.Atoi(string(p.State))
+		if err != nil || offset < 0 {
+			return nil, fmt.Errorf("Invalid paging state %q", p.State)
+		}
+	}
+	if offset > len(result) {
+		offset = len(result)
+	}
+	result = result[offset:]
+
+	p.State = nil
+	if p.PageSize > 0 && p.PageSize < len(result) {
+		result = result[:p.PageSize]
+		p.State = []byte(strconv.Itoa(offset + p.PageSize))
+	}
+	return result, nil
+}
+
 func (q *MockFilter) assignResult(records interface{}, out interface{}) error {
 	return decodeResult(records, out)
 }
@@ -505,7 +1292,7 @@ func (q *MockFilter) ReadOne(out interface{}) Op {
 	return newOp(func(m mockOp) error {
 		slicePtrVal := reflect.New(reflect.SliceOf(reflect.ValueOf(out).Elem().Type()))
 
-		err := q.Read(slicePtrVal.Interface()).Run()
+		err := q.Read(slicePtrVal.Interface()).WithOptions(m.options).Run()
 		if err != nil {
 			return err
 		}
@@ -1,11 +1,18 @@
 package gocassa
 
 import (
+	"context"
+	"math/big"
+	"net"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/gocql/gocql"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"gopkg.in/inf.v0"
 )
 
 type user struct {
@@ -112,6 +119,82 @@ func (s *MockSuite) TestTableRead() {
 	s.NoError(op1.Add(op2).Run())
 }
 
+func (s *MockSuite) TestTableIter() {
+	u1, u2, u3, u4 := s.insertUsers()
+
+	iter := s.tbl.Where(Eq("Pk1", 1), In("Pk2", 1, 2)).Iter()
+	var users []user
+	var u user
+	for iter.Next(&u) {
+		users = append(users, u)
+	}
+	s.NoError(iter.Close())
+	s.Equal([]user{u1, u4, u3, u2}, users)
+
+	// Rows written while iterating are picked up if they sort after the current position
+	iter = s.tbl.Where(Eq("Pk1", 1), Eq("Pk2", 1)).Iter()
+	s.True(iter.Next(&u))
+	s.Equal(u1, u)
+	u5 := user{Pk1: 1, Pk2: 1, Ck1: 3, Ck2: 1, Name: "Jim"}
+	s.NoError(s.tbl.Set(u5).Run())
+	users = nil
+	for iter.Next(&u) {
+		users = append(users, u)
+	}
+	s.NoError(iter.Err())
+	s.Equal([]user{u4, u3, u5}, users)
+
+	iter = s.tbl.WithOptions(Options{Limit: 1}).Where(Eq("Pk1", 1), Eq("Pk2", 1)).Iter()
+	s.True(iter.Next(&u))
+	s.False(iter.Next(&u))
+	s.NoError(iter.Close())
+
+	iter = s.tbl.Where(Eq("Pk1", 1)).Iter()
+	s.False(iter.Next(&u))
+	s.Error(iter.Err())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	iter = s.tbl.Where(Eq("Pk1", 1), Eq("Pk2", 1)).IterContext(ctx)
+	s.True(iter.Next(&u))
+	cancel()
+	s.False(iter.Next(&u))
+	s.Equal(context.Canceled, iter.Close())
+
+	// Rows are iterated in the clustering order of the table, as they are read
+	desc := s.tbl.WithOptions(Options{ClusteringOrder: []ClusteringOrderColumn{{Column: "Ck1", Direction: DESC}}})
+	read := []user{}
+	s.NoError(desc.Where(Eq("Pk1", 1), In("Pk2", 1, 2)).Read(&read).Run())
+	iter = desc.Where(Eq("Pk1", 1), In("Pk2", 1, 2)).Iter()
+	users = nil
+	for iter.Next(&u) {
+		users = append(users, u)
+	}
+	s.NoError(iter.Close())
+	s.Equal(read, users)
+	s.Equal(u5, users[0])
+}
+
+func (s *MockSuite) TestTablePaging() {
+	u1, u2, u3, u4 := s.insertUsers()
+
+	paging := &Paging{PageSize: 3}
+	var users []user
+	op := s.tbl.Where(Eq("Pk1", 1), In("Pk2", 1, 2)).Read(&users).WithOptions(Options{Paging: paging})
+	s.NoError(op.Run())
+	s.Equal([]user{u1, u4, u3}, users)
+	s.True(paging.HasMore())
+
+	// Resume from a cursor handed out to a client
+	paging = &Paging{PageSize: 3, State: paging.State}
+	op = s.tbl.Where(Eq("Pk1", 1), In("Pk2", 1, 2)).Read(&users).WithOptions(Options{Paging: paging})
+	s.NoError(op.Run())
+	s.Equal([]user{u2}, users)
+	s.False(paging.HasMore())
+
+	paging = &Paging{PageSize: 3, State: []byte("garbage")}
+	s.Error(s.tbl.Where(Eq("Pk1", 1), Eq("Pk2", 1)).Read(&users).WithOptions(Options{Paging: paging}).Run())
+}
+
 func (s *MockSuite) TestTableUpdate() {
 	s.insertUsers()
 
@@ -138,6 +221,453 @@ func (s *MockSuite) TestTableUpdate() {
 	}
 }
 
+func (s *MockSuite) TestTableSetIfNotExists() {
+	u1, _, _, _ := s.insertUsers()
+
+	result := &CASResult{}
+	s.NoError(s.tbl.SetIfNotExists(user{Pk1: 1, Pk2: 1, Ck1: 1, Ck2: 1, Name: "Jim"}, result).Run())
+	s.False(result.Applied)
+	var current user
+	s.NoError(result.Decode(&current))
+	s.Equal(u1, current)
+
+	u6 := user{Pk1: 3, Pk2: 1, Ck1: 1, Ck2: 1, Name: "Jim"}
+	s.NoError(s.tbl.SetIfNotExists(u6, result).Run())
+	s.True(result.Applied)
+	s.Empty(result.Current)
+
+	var u user
+	s.NoError(s.tbl.Where(Eq("Pk1", 3), Eq("Pk2", 1), Eq("Ck1", 1), Eq("Ck2", 1)).ReadOne(&u).Run())
+	s.Equal(u6, u)
+}
+
+func (s *MockSuite) TestTableUpdateIf() {
+	s.insertUsers()
+	relations := []Relation{Eq("Pk1", 1), Eq("Pk2", 1), Eq("Ck1", 1), Eq("Ck2", 1)}
+
+	result := &CASResult{}
+	s.NoError(s.tbl.Where(relations...).UpdateIf([]Relation{Eq("Name", "Joe")}, map[string]interface{}{
+		"Name": "Jim",
+	}, result).Run())
+	s.False(result.Applied)
+	s.Equal(map[string]interface{}{"Name": "John"}, result.Current)
+
+	s.NoError(s.tbl.Where(relations...).UpdateIf([]Relation{Eq("Name", "John")}, map[string]interface{}{
+		"Name": "Jim",
+	}, result).Run())
+	s.True(result.Applied)
+
+	var u user
+	s.NoError(s.tbl.Where(relations...).ReadOne(&u).Run())
+	s.Equal("Jim", u.Name)
+
+	// IF EXISTS
+	s.NoError(s.tbl.Where(Eq("Pk1", 9), Eq("Pk2", 1), Eq("Ck1", 1), Eq("Ck2", 1)).UpdateIf(nil, map[string]interface{}{
+		"Name": "Jim",
+	}, result).Run())
+	s.False(result.Applied)
+	s.Empty(result.Current)
+
+	s.Error(s.tbl.Where(Eq("Pk1", 1), Eq("Pk2", 1), Eq("Ck1", 1), In("Ck2", 1, 2)).UpdateIf(nil, map[string]interface{}{
+		"Name": "Jim",
+	}, result).Run())
+}
+
+func (s *MockSuite) TestTableDeleteIf() {
+	s.insertUsers()
+	relations := []Relation{Eq("Pk1", 1), Eq("Pk2", 1), Eq("Ck1", 1), Eq("Ck2", 1)}
+
+	result := &CASResult{}
+	s.NoError(s.tbl.Where(relations...).DeleteIf([]Relation{Eq("Name", "Joe")}, result).Run())
+	s.False(result.Applied)
+
+	s.NoError(s.tbl.Where(relations...).DeleteIf(nil, result).Run())
+	s.True(result.Applied)
+
+	var u user
+	s.Equal(RowNotFoundError{}, s.tbl.Where(relations...).ReadOne(&u).Run())
+}
+
+func (s *MockSuite) TestTableTimestamp() {
+	relations := []Relation{Eq("Pk1", 1), Eq("Pk2", 1), Eq("Ck1", 1), Eq("Ck2", 1)}
+	ts := time.Now().Add(time.Hour)
+	u1 := user{Pk1: 1, Pk2: 1, Ck1: 1, Ck2: 1, Name: "John"}
+	s.NoError(s.tbl.Set(u1).WithOptions(Options{Timestamp: ts}).Run())
+
+	// Older writes lose
+	s.NoError(s.tbl.Where(relations...).Update(map[string]interface{}{"Name": "Joe"}).Run())
+	s.NoError(s.tbl.Set(user{Pk1: 1, Pk2: 1, Ck1: 1, Ck2: 1, Name: "Jim"}).WithOptions(Options{Timestamp: ts.Add(-time.Second)}).Run())
+	var u user
+	s.NoError(s.tbl.Where(relations...).ReadOne(&u).Run())
+	s.Equal(u1, u)
+
+	// ... and so do older deletes
+	s.NoError(s.tbl.Where(relations...).Delete().Run())
+	s.NoError(s.tbl.Where(relations...).ReadOne(&u).Run())
+	s.Equal(u1, u)
+
+	s.NoError(s.tbl.Where(relations...).Update(map[string]interface{}{"Name": "Jane"}).WithOptions(Options{Timestamp: ts.Add(time.Second)}).Run())
+	s.NoError(s.tbl.Where(relations...).ReadOne(&u).Run())
+	s.Equal("Jane", u.Name)
+
+	s.NoError(s.tbl.Where(relations...).Delete().WithOptions(Options{Timestamp: ts.Add(time.Minute)}).Run())
+	s.Equal(RowNotFoundError{}, s.tbl.Where(relations...).ReadOne(&u).Run())
+}
+
+func (s *MockSuite) TestTableTimestampDeleteBetweenWrites() {
+	relations := []Relation{Eq("Pk1", 2), Eq("Pk2", 1), Eq("Ck1", 1), Eq("Ck2", 1)}
+	ts := time.Now().Add(time.Hour)
+	s.NoError(s.tbl.Set(user{Pk1: 2, Pk2: 1, Ck1: 1, Ck2: 1, Name: "old"}).WithOptions(Options{Timestamp: ts.Add(100 * time.Millisecond)}).Run())
+	s.NoError(s.tbl.Where(relations...).Update(map[string]interface{}{"Name": "new"}).WithOptions(Options{Timestamp: ts.Add(200 * time.Millisecond)}).Run())
+
+	// A delete older than the update only deletes the columns written before it, the row remains with the others
+	s.NoError(s.tbl.Where(relations...).Delete().WithOptions(Options{Timestamp: ts.Add(150 * time.Millisecond)}).Run())
+	var u user
+	s.NoError(s.tbl.Where(relations...).ReadOne(&u).Run())
+	s.Equal(user{Pk1: 2, Pk2: 1, Ck1: 1, Ck2: 1, Name: "new"}, u)
+
+	// Rows which only hold keys outlive the deletes older than their latest write
+	type pair struct {
+		Left  int
+		Right int
+	}
+	tbl := s.ks.Table("pairs", pair{}, Keys{PartitionKeys: []string{"Left"}, ClusteringColumns: []string{"Right"}})
+	s.NoError(tbl.Set(pair{Left: 1, Right: 2}).WithOptions(Options{Timestamp: ts.Add(100 * time.Millisecond)}).Run())
+	s.NoError(tbl.Set(pair{Left: 1, Right: 2}).WithOptions(Options{Timestamp: ts.Add(200 * time.Millisecond)}).Run())
+	s.NoError(tbl.Where(Eq("Left", 1), Eq("Right", 2)).Delete().WithOptions(Options{Timestamp: ts.Add(150 * time.Millisecond)}).Run())
+	p := pair{}
+	s.NoError(tbl.Where(Eq("Left", 1), Eq("Right", 2)).ReadOne(&p).Run())
+	s.NoError(tbl.Where(Eq("Left", 1), Eq("Right", 2)).Delete().WithOptions(Options{Timestamp: ts.Add(200 * time.Millisecond)}).Run())
+	s.Equal(RowNotFoundError{}, tbl.Where(Eq("Left", 1), Eq("Right", 2)).ReadOne(&p).Run())
+}
+
+func (s *MockSuite) TestTableWriteTimeAndTTL() {
+	type userWithMetadata struct {
+		Pk1           int
+		Name          string
+		NameWriteTime int64 `cql:"writetime(Name)"`
+		NameTTL       int   `cql:"ttl(Name)"`
+	}
+	now := time.Now()
+	defer SetMockClock(func() time.Time { return now })()
+
+	tbl := s.ks.MapTable("users_meta", "Pk1", userWithMetadata{})
+	ts := now.Add(-time.Hour)
+	s.NoError(tbl.Set(userWithMetadata{Pk1: 1, Name: "John"}).WithOptions(Options{Timestamp: ts}).Run())
+	s.NoError(tbl.Update(1, map[string]interface{}{"Name": "Joe"}).WithOptions(Options{TTL: time.Minute}).Run())
+
+	var u userWithMetadata
+	s.NoError(tbl.Read(1, &u).Run())
+	s.Equal("Joe", u.Name)
+	s.Equal(now.UnixNano()/int64(time.Microsecond), u.NameWriteTime)
+	s.Equal(60, u.NameTTL)
+
+	// Metadata can be selected explicitly too
+	var m map[string]interface{}
+	s.NoError(tbl.Read(1, &m).WithOptions(Options{Select: []string{"Name", "WRITETIME(Name)", "TTL(Name)"}}).Run())
+	s.Equal(now.UnixNano()/int64(time.Microsecond), m["writetime(name)"])
+	s.Equal(60, m["ttl(name)"])
+
+	// Expired columns disappear, and so do rows with nothing left
+	SetMockClock(func() time.Time { return now.Add(59 * time.Second) })
+	s.NoError(tbl.Read(1, &u).Run())
+	s.Equal(1, u.NameTTL)
+	SetMockClock(func() time.Time { return now.Add(time.Minute) })
+	s.NoError(tbl.Read(1, &u).Run())
+	s.Equal("", u.Name)
+	s.Equal(int64(0), u.NameWriteTime)
+
+	s.NoError(tbl.Set(userWithMetadata{Pk1: 2, Name: "Jane"}).WithOptions(Options{TTL: time.Second}).Run())
+	SetMockClock(func() time.Time { return now.Add(time.Hour) })
+	s.Equal(RowNotFoundError{}, tbl.Read(2, &u).Run())
+}
+
+func (s *MockSuite) TestTableSchema() {
+	tables, err := s.ks.Tables()
+	s.NoError(err)
+	s.Empty(tables)
+
+	tbl, err := s.ks.TableFromStruct("events", Event{})
+	s.NoError(err)
+	schema, err := s.ks.TableSchema(tbl.Name())
+	s.NoError(err)
+	s.Nil(schema)
+
+	// Tables exist once created
+	s.NoError(tbl.CreateIfNotExist())
+	s.NoError(s.mapTbl.CreateIfNotExist())
+	tables, err = s.ks.Tables()
+	s.NoError(err)
+	s.Equal([]string{"events__user_id__created_id", "users_map_pk1"}, tables)
+	ok, err := s.ks.Exists("users_map_Pk1")
+	s.NoError(err)
+	s.True(ok)
+
+	schema, err = s.ks.TableSchema(tbl.Name())
+	s.NoError(err)
+	s.Equal([]string{"user_id"}, schema.PartitionKeys)
+	s.Equal([]string{"created", "id"}, schema.ClusteringColumns)
+	s.Equal([]ClusteringOrderColumn{{Direction: DESC, Column: "created"}, {Direction: ASC, Column: "id"}}, schema.ClusteringOrder)
+	c, ok := schema.Column("owner")
+	s.True(ok)
+	s.Equal(ColumnSchema{Name: "owner", Type: "text", Kind: StaticColumn}, c)
+
+	s.NoError(s.ks.(*mockKeySpace).DropTable(tbl.Name()))
+	ok, err = s.ks.Exists(tbl.Name())
+	s.NoError(err)
+	s.False(ok)
+}
+
+func (s *MockSuite) TestTableUDTs() {
+	tbl := s.ks.MapTable("venues", "Id", Venue{})
+	v := Venue{
+		Id:       "1",
+		Place:    Place{Name: "Hall", Location: Location{Lat: 1, Lng: 2}},
+		Previous: &Place{Name: "Barn"},
+		Others:   []Place{{Name: "Annex"}, {Name: "Garden", Location: Location{Lat: 3}}},
+		Nearby:   map[string]Location{"park": {Lat: 4, Lng: 5}},
+	}
+	s.NoError(tbl.Set(v).Run())
+	s.NoError(tbl.Set(Venue{Id: "2"}).Run())
+
+	var read Venue
+	s.NoError(tbl.Read("1", &read).Run())
+	s.Equal(v, read)
+	read = Venue{}
+	s.NoError(tbl.Read("2", &read).Run())
+	s.Nil(read.Previous)
+
+	s.NoError(tbl.Update("2", map[string]interface{}{"Place": Place{Name: "Shed"}}).Run())
+	s.NoError(tbl.Read("2", &read).Run())
+	s.Equal("Shed", read.Place.Name)
+}
+
+func (s *MockSuite) TestTableCollectionTypes() {
+	tbl := s.ks.MapTable("routes", "Id", Route{})
+	r := Route{
+		Id:       "1",
+		Tags:     []string{"b", "a", "b"},
+		Position: Point{X: 1, Y: 2},
+		Bounds:   [2]int{3, 4},
+		History:  []float64{1.5},
+		Legs:     map[string][]int{"north": {1, 2}},
+		Stops:    [][]string{{"x", "y"}},
+	}
+	s.NoError(tbl.Set(r).Run())
+
+	// Sets are sorted and hold no duplicates
+	var read Route
+	s.NoError(tbl.Read("1", &read).Run())
+	r.Tags = []string{"a", "b"}
+	s.Equal(r, read)
+
+	s.NoError(tbl.Update("1", map[string]interface{}{
+		"tags":    SetAdd("c"),
+		"history": []float64{2.5},
+		"legs":    MapSetField("south", []int{3}),
+	}).Run())
+	s.NoError(tbl.Update("1", map[string]interface{}{
+		"tags":  SetRemove("a"),
+		"stops": ListAppend([]string{"z"}),
+	}).Run())
+	read = Route{}
+	s.NoError(tbl.Read("1", &read).Run())
+	s.Equal([]string{"b", "c"}, read.Tags)
+	s.Equal([]float64{2.5}, read.History)
+	s.Equal(map[string][]int{"north": {1, 2}, "south": {3}}, read.Legs)
+	s.Equal([][]string{{"x", "y"}, {"z"}}, read.Stops)
+
+	s.Error(tbl.Update("1", map[string]interface{}{"stops": ListSetAtIndex(5, []string{})}).Run())
+}
+
+func (s *MockSuite) TestTableScalarTypes() {
+	tbl, err := s.ks.TableFromStruct("readings", Reading{})
+	s.NoError(err)
+	r := Reading{
+		Id:       gocql.TimeUUID(),
+		Day:      time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC),
+		At:       9 * time.Hour,
+		Interval: time.Minute,
+		Address:  net.ParseIP("::1"),
+		Value:    inf.NewDec(314, 2),
+		Count:    big.NewInt(42),
+		Small:    -3,
+		Tiny:     7,
+	}
+	s.NoError(tbl.Set(r).Run())
+
+	var read Reading
+	s.NoError(tbl.Where(Eq("id", r.Id)).ReadOne(&read).Run())
+	s.Equal(r, read)
+}
+
+func (s *MockSuite) TestTableProperties() {
+	now := time.Now()
+	defer SetMockClock(func() time.Time { return now })()
+
+	tbl := s.ks.MapTable("users_ttl", "Pk1", user{}).WithOptions(Options{
+		Properties: &TableProperties{DefaultTTL: time.Minute},
+	})
+	s.Error(tbl.(TableChanger).Alter())
+	s.NoError(tbl.(TableChanger).Create())
+	s.NoError(tbl.(TableChanger).Alter())
+	stmt, err := tbl.(TableChanger).AlterStatement()
+	s.NoError(err)
+	s.Contains(stmt, "users_ttl_map_Pk1 WITH default_time_to_live = 60")
+
+	// Rows are written with the default TTL of the table, unless the op sets one
+	s.NoError(tbl.Set(user{Pk1: 1, Name: "John"}).Run())
+	s.NoError(tbl.Set(user{Pk1: 2, Name: "Jane"}).WithOptions(Options{TTL: time.Hour}).Run())
+	SetMockClock(func() time.Time { return now.Add(time.Minute) })
+	var u user
+	s.Equal(RowNotFoundError{}, tbl.Read(1, &u).Run())
+	s.NoError(tbl.Read(2, &u).Run())
+	s.Equal("Jane", u.Name)
+
+	s.Error(s.ks.MapTable("users_ttl", "Pk1", user{}).(TableChanger).Alter())
+	_, err = s.ks.MapTable("users_ttl", "Pk1", user{}).(TableChanger).AlterStatement()
+	s.Error(err)
+}
+
+func (s *MockSuite) TestCounterTable() {
+	tbl, err := s.ks.CounterTable("pageviews", Keys{PartitionKeys: []string{"Page"}, ClusteringColumns: []string{"Day"}}, PageViews{})
+	s.NoError(err)
+	key := map[string]interface{}{"Page": "/", "Day": "2020-02-29"}
+	s.NoError(tbl.Increment(key, map[string]int{"Views": 2}).Run())
+	s.NoError(tbl.Increment(key, map[string]int{"Views": 3, "Visits": -1}).Run())
+
+	var views PageViews
+	s.NoError(tbl.Read(key, &views).Run())
+	s.Equal(PageViews{Page: "/", Day: "2020-02-29", Views: 5, Visits: -1}, views)
+
+	// Setting a row with counters increments them as well
+	mapTbl := s.ks.MapTable("counters", "Id", CustomerWithCounter{})
+	s.NoError(mapTbl.Set(CustomerWithCounter{Id: "1", Counter: 2}).Run())
+	s.NoError(mapTbl.Set(CustomerWithCounter{Id: "1", Counter: 3}).Run())
+	var c CustomerWithCounter
+	s.NoError(mapTbl.Read("1", &c).Run())
+	s.Equal(Counter(5), c.Counter)
+
+	s.NoError(tbl.Reset(key).Run())
+	s.Equal(RowNotFoundError{}, tbl.Read(key, &views).Run())
+}
+
+func (s *MockSuite) TestQueueTableReindexedWhilePeeking() {
+	tbl := s.ks.QueueTable("outbox", "Id", 1, time.Hour, OutboxEvent{})
+	// An item of a bucket which is over, eg. one enqueued again with its original id
+	event := OutboxEvent{Id: gocql.UUIDFromTime(time.Now().Add(-3 * time.Hour)), Topic: "orders"}
+	s.NoError(tbl.Enqueue(event).Run())
+	s.NoError(tbl.Ack(event.Id).Run())
+
+	// Its bucket is read empty by Peek, then the item is enqueued again before the bucket is removed from the index
+	peeked := []OutboxEvent{}
+	cleanup, err := tbl.Peek(1, &peeked).(*deferredOp).op(context.Background())
+	s.NoError(err)
+	s.Empty(peeked)
+	now := time.Now()
+	s.NoError(tbl.Enqueue(event).WithOptions(Options{Timestamp: now.Add(time.Second)}).Run())
+	restore := SetMockClock(func() time.Time { return now.Add(2 * time.Second) })
+	s.NoError(cleanup.Run())
+	restore()
+
+	s.NoError(tbl.Peek(1, &peeked).Run())
+	s.Equal([]OutboxEvent{event}, peeked)
+}
+
+func (s *MockSuite) TestLeaseTableExpiry() {
+	now := time.Now()
+	defer SetMockClock(func() time.Time { return now })()
+	tbl := s.ks.LeaseTable("locks")
+	var ok bool
+	var holder string
+	s.NoError(tbl.Acquire("leader", "a", time.Minute, &ok).Run())
+	s.True(ok)
+
+	// Renewing the lease, or acquiring it again, extends it
+	SetMockClock(func() time.Time { return now.Add(50 * time.Second) })
+	s.NoError(tbl.Renew("leader", "a", time.Minute, &ok).Run())
+	s.True(ok)
+	SetMockClock(func() time.Time { return now.Add(100 * time.Second) })
+	s.NoError(tbl.Acquire("leader", "a", time.Minute, &ok).Run())
+	s.True(ok)
+	SetMockClock(func() time.Time { return now.Add(150 * time.Second) })
+	s.NoError(tbl.Holder("leader", &holder).Run())
+	s.Equal("a", holder)
+
+	// The lease expires if it is not renewed
+	SetMockClock(func() time.Time { return now.Add(160 * time.Second) })
+	s.NoError(tbl.Holder("leader", &holder).Run())
+	s.Equal("", holder)
+	s.NoError(tbl.Renew("leader", "a", time.Minute, &ok).Run())
+	s.False(ok)
+	s.NoError(tbl.Acquire("leader", "b", time.Minute, &ok).Run())
+	s.True(ok)
+	s.NoError(tbl.Holder("leader", &holder).Run())
+	s.Equal("b", holder)
+}
+
+func (s *MockSuite) TestLeaseTableContention() {
+	tbl := s.ks.LeaseTable("contended")
+	acquired := make([]bool, 20)
+	errs := make([]error, len(acquired))
+	wg := sync.WaitGroup{}
+	for i := range acquired {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = tbl.Acquire("job", strconv.Itoa(i), time.Minute, &acquired[i]).Run()
+		}(i)
+	}
+	wg.Wait()
+	holders := []string{}
+	for i, ok := range acquired {
+		s.NoError(errs[i])
+		if ok {
+			holders = append(holders, strconv.Itoa(i))
+		}
+	}
+	s.Len(holders, 1)
+	var holder string
+	s.NoError(tbl.Holder("job", &holder).Run())
+	s.Equal(holders[0], holder)
+}
+
+func (s *MockSuite) TestTableStaticColumns() {
+	tbl, err := s.ks.TableFromStruct("events", Event{})
+	s.NoError(err)
+	now := time.Now().Truncate(time.Second)
+	for i := 0; i < 3; i++ {
+		e := Event{UserId: "1", Created: now.Add(time.Duration(i) * time.Second), Id: strconv.Itoa(i), Payload: "p"}
+		s.NoError(tbl.Set(e).Run())
+	}
+	s.NoError(tbl.Set(Event{UserId: "2", Created: now, Id: "0", Owner: "Jane"}).Run())
+
+	// Static columns are shared by the rows of the partition
+	s.NoError(tbl.Where(Eq("user_id", "1"), Eq("created", now), Eq("id", "0")).Update(map[string]interface{}{
+		"owner": "John",
+	}).Run())
+	var events []Event
+	s.NoError(tbl.Where(Eq("user_id", "1")).Read(&events).Run())
+	s.Len(events, 3)
+	for _, e := range events {
+		s.Equal("John", e.Owner)
+	}
+
+	// ... and survive the deletion of rows, but not of the partition
+	s.NoError(tbl.Where(Eq("user_id", "1"), Eq("created", now), Eq("id", "0")).Delete().Run())
+	var e Event
+	s.NoError(tbl.Where(Eq("user_id", "1"), Eq("created", now.Add(time.Second)), Eq("id", "1")).ReadOne(&e).Run())
+	s.Equal("John", e.Owner)
+	s.NoError(tbl.Where(Eq("user_id", "1")).Delete().Run())
+	s.NoError(tbl.Set(Event{UserId: "1", Created: now, Id: "0"}).Run())
+	s.NoError(tbl.Where(Eq("user_id", "1"), Eq("created", now), Eq("id", "0")).ReadOne(&e).Run())
+	s.Equal("", e.Owner)
+
+	s.NoError(tbl.Where(Eq("user_id", "2")).Read(&events).Run())
+	s.Len(events, 1)
+	s.Equal("Jane", events[0].Owner)
+}
+
 func (s *MockSuite) TestTableDeleteOne() {
 	s.insertUsers()
 
@@ -247,6 +777,36 @@ func (s *MockSuite) TestMultiMapTableList() {
 	s.Equal("Joe", users[0].Name)
 }
 
+func (s *MockSuite) TestMultiMapTableListPaging() {
+	s.insertUsers()
+
+	paging := &Paging{PageSize: 1}
+	var names []string
+	for {
+		var users []user
+		s.NoError(s.mmapTbl.List(1, nil, 0, &users).WithOptions(Options{Paging: paging}).Run())
+		s.Len(users, 1)
+		names = append(names, users[0].Name)
+		if !paging.HasMore() {
+			break
+		}
+	}
+	s.Equal([]string{"Jane", "Joe"}, names)
+}
+
+func (s *MockSuite) TestMultiMapTableListIter() {
+	s.insertUsers()
+
+	iter := s.mmapTbl.ListIter(1, 0, 10)
+	var names []string
+	var u user
+	for iter.Next(&u) {
+		names = append(names, u.Name)
+	}
+	s.NoError(iter.Close())
+	s.Equal([]string{"Jane", "Joe"}, names)
+}
+
 func (s *MockSuite) TestMultiMapTableUpdate() {
 	s.insertUsers()
 
@@ -298,6 +858,41 @@ func (s *MockSuite) TestTimeSeriesTableList() {
 	s.Equal(points[2], ps[1])
 }
 
+func (s *MockSuite) TestTimeSeriesTableListPaging() {
+	points := s.insertPoints()
+
+	paging := &Paging{PageSize: 2}
+	var ps []point
+	s.NoError(s.tsTbl.List(points[0].Time, points[2].Time, &ps).WithOptions(Options{Paging: paging}).Run())
+	s.Equal(points[:2], ps)
+	s.True(paging.HasMore())
+
+	s.NoError(s.tsTbl.List(points[0].Time, points[2].Time, &ps).WithOptions(Options{Paging: paging}).Run())
+	s.Equal(points[2:], ps)
+	s.False(paging.HasMore())
+}
+
+func (s *MockSuite) TestTimeSeriesTableListIter() {
+	points := s.insertPoints()
+
+	iter := s.tsTbl.ListIter(points[0].Time, points[2].Time)
+	var ps []point
+	var p point
+	for iter.Next(&p) {
+		ps = append(ps, p)
+	}
+	s.NoError(iter.Close())
+	s.Equal(points, ps)
+
+	iter = s.mtsTbl.ListIter("John", points[0].Time, points[2].Time)
+	ps = nil
+	for iter.Next(&p) {
+		ps = append(ps, p)
+	}
+	s.NoError(iter.Close())
+	s.Equal([]point{points[0], points[2]}, ps)
+}
+
 func (s *MockSuite) TestTimeSeriesTableUpdate() {
 	points := s.insertPoints()
 
@@ -385,6 +980,53 @@ func (s *MockSuite) TestEmbedMapRead() {
 	s.Equal(expectedAddresses[1], actualAddress)
 }
 
+func (s *MockSuite) TestRunBatch() {
+	s.insertUsers()
+	op := s.mapTbl.Update(1, map[string]interface{}{"Name": "foo"}).Add(s.mapTbl.Delete(2))
+	s.NoError(op.RunBatch(UnloggedBatch))
+
+	var users []user
+	s.NoError(s.mapTbl.MultiRead([]interface{}{1, 2}, &users).Run())
+	s.Len(users, 1)
+	s.Equal("foo", users[0].Name)
+}
+
+func (s *MockSuite) TestRunParallel() {
+	op := Noop()
+	for i := 0; i < 100; i++ {
+		u := user{Pk1: i % 3, Pk2: i, Ck1: 1, Ck2: 1, Name: "John"}
+		op = op.Add(s.mapTbl.Set(u), s.mmapTbl.Set(u))
+		// Reads through a copy of the table sharing the same rows
+		op = op.Add(s.mmapTbl.List(i%3, nil, 10, &[]user{}))
+	}
+	s.NoError(op.RunParallel(8))
+
+	var users []user
+	s.NoError(s.mmapTbl.List(0, nil, 0, &users).Run())
+	s.Len(users, 34)
+
+	failing := s.tbl.Where(Eq("Pk1", 1)).Update(map[string]interface{}{"Name": "foo"})
+	err := s.mapTbl.Delete(1).Add(failing, s.mapTbl.Delete(2), failing).RunParallel(0)
+	s.IsType(MultiError{}, err)
+	s.Len(err, 2)
+
+	var u user
+	s.Equal(RowNotFoundError{}, s.mapTbl.Read(2, &u).Run())
+}
+
+func (s *MockSuite) TestRunContextCancelled() {
+	s.insertUsers()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	s.Equal(context.Canceled, s.mapTbl.Delete(1).RunContext(ctx))
+	s.Equal(context.Canceled, s.mapTbl.Delete(1).Add(s.mapTbl.Delete(2)).RunContext(ctx))
+
+	var u user
+	s.NoError(s.mapTbl.Read(1, &u).RunContext(context.Background()))
+	s.Equal("Jane", u.Name)
+}
+
 // Helper functions
 func (s *MockSuite) insertPoints() []point {
 	points := []point{
@@ -15,6 +15,8 @@ const (
 	modifierMapSetFields
 	modifierMapSetField
 	modifierCounterIncrement
+	modifierSetAdd
+	modifierSetRemove
 )
 
 type Modifier struct {
@@ -54,6 +56,22 @@ func ListRemove(value interface{}) Modifier {
 	}
 }
 
+// SetAdd adds a value to the set
+func SetAdd(value interface{}) Modifier {
+	return Modifier{
+		op:   modifierSetAdd,
+		args: []interface{}{value},
+	}
+}
+
+// SetRemove removes a value from the set
+func SetRemove(value interface{}) Modifier {
+	return Modifier{
+		op:   modifierSetRemove,
+		args: []interface{}{value},
+	}
+}
+
 // This uses DELETE, not UPDATE
 // Removes an element at a specific index from the list
 // func ListRemoveAtIndex(index int, value interface{}) Modifier {
@@ -104,9 +122,12 @@ func (m Modifier) cql(name string) (string, []interface{}) {
 	case modifierListSetAtIndex:
 		str = fmt.Sprintf("%s[?] = ?", name)
 		vals = append(vals, m.args[0], m.args[1])
-	case modifierListRemove:
+	case modifierListRemove, modifierSetRemove:
 		str = fmt.Sprintf("%s = %s - ?", name, name)
 		vals = append(vals, []interface{}{m.args[0]})
+	case modifierSetAdd:
+		str = fmt.Sprintf("%s = %s + ?", name, name)
+		vals = append(vals, []interface{}{m.args[0]})
 	case modifierMapSetFields:
 		fields, ok := m.args[0].(map[string]interface{})
 		if !ok {
@@ -114,17 +135,14 @@ func (m Modifier) cql(name string) (string, []interface{}) {
 		}
 
 		buf := new(bytes.Buffer)
-		i := 0
-		for k, v := range fields {
+		for i, k := range sortedKeys(fields) {
 			if i > 0 {
 				buf.WriteString(", ")
 			}
 
-			fieldStmt, fieldVals := MapSetField(k, v).cql(name)
+			fieldStmt, fieldVals := MapSetField(k, fields[k]).cql(name)
 			buf.WriteString(fieldStmt)
 			vals = append(vals, fieldVals...)
-
-			i++
 		}
 		str = buf.String()
 	case modifierMapSetField:
@@ -27,6 +27,14 @@ func (mm *multimapMkT) MultiRead(field, id map[string]interface{}, pointerToASli
 }
 
 func (mm *multimapMkT) List(field, startId map[string]interface{}, limit int, pointerToASlice interface{}) Op {
+	return mm.list(field, startId, limit).Read(pointerToASlice)
+}
+
+func (mm *multimapMkT) ListIter(field, startId map[string]interface{}, limit int) Iterator {
+	return mm.list(field, startId, limit).Iter()
+}
+
+func (mm *multimapMkT) list(field, startId map[string]interface{}, limit int) Filter {
 	rels := mm.ListOfEqualRelations(field, nil)
 	if startId != nil {
 		for _, field := range mm.idField {
@@ -35,7 +43,7 @@ func (mm *multimapMkT) List(field, startId map[string]interface{}, limit int, po
 			}
 		}
 	}
-	return mm.WithOptions(Options{Limit: limit}).(*multimapMkT).Where(rels...).Read(pointerToASlice)
+	return mm.WithOptions(Options{Limit: limit}).(*multimapMkT).Where(rels...)
 }
 
 func (mm *multimapMkT) WithOptions(o Options) MultimapMkTable {
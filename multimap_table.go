@@ -27,11 +27,19 @@ func (mm *multimapT) MultiRead(field interface{}, ids []interface{}, pointerToAS
 }
 
 func (mm *multimapT) List(field, startId interface{}, limit int, pointerToASlice interface{}) Op {
+	return mm.list(field, startId, limit).Read(pointerToASlice)
+}
+
+func (mm *multimapT) ListIter(field, startId interface{}, limit int) Iterator {
+	return mm.list(field, startId, limit).Iter()
+}
+
+func (mm *multimapT) list(field, startId interface{}, limit int) Filter {
 	rels := []Relation{Eq(mm.fieldToIndexBy, field)}
 	if startId != nil {
 		rels = append(rels, GTE(mm.idField, startId))
 	}
-	return mm.WithOptions(Options{Limit: limit}).(*multimapT).Where(rels...).Read(pointerToASlice)
+	return mm.WithOptions(Options{Limit: limit}).(*multimapT).Where(rels...)
 }
 
 func (mm *multimapT) WithOptions(o Options) MultimapTable {
@@ -1,5 +1,11 @@
 package gocassa
 
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
 type multiOp []Op
 
 func Noop() Op {
@@ -7,32 +13,136 @@ func Noop() Op {
 }
 
 func (mo multiOp) Run() error {
+	return mo.RunContext(context.Background())
+}
+
+func (mo multiOp) RunContext(ctx context.Context) error {
 	if err := mo.Preflight(); err != nil {
 		return err
 	}
 	for _, op := range mo {
-		if err := op.Run(); err != nil {
+		if err := op.RunContext(ctx); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+func (mo multiOp) RunParallel(concurrency int) error {
+	return mo.RunParallelContext(context.Background(), concurrency)
+}
+
+func (mo multiOp) RunParallelContext(ctx context.Context, concurrency int) error {
+	if err := mo.Preflight(); err != nil {
+		return err
+	}
+	if concurrency < 1 || concurrency > len(mo) {
+		concurrency = len(mo)
+	}
+
+	errs := make([]error, len(mo))
+	sem := make(chan struct{}, concurrency)
+	wg := sync.WaitGroup{}
+	for i, op := range mo {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, op Op) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			errs[i] = op.RunContext(ctx)
+		}(i, op)
+	}
+	wg.Wait()
+
+	var multiErr MultiError
+	for _, err := range errs {
+		if err != nil {
+			multiErr = append(multiErr, err)
+		}
+	}
+	if len(multiErr) > 0 {
+		return multiErr
+	}
+	return nil
+}
+
 func (mo multiOp) RunAtomically() error {
+	return mo.RunAtomicallyContext(context.Background())
+}
+
+func (mo multiOp) RunAtomicallyContext(ctx context.Context) error {
+	return mo.RunBatchContext(ctx, LoggedBatch)
+}
+
+func (mo multiOp) RunBatch(batchType BatchType) error {
+	return mo.RunBatchContext(context.Background(), batchType)
+}
+
+func (mo multiOp) RunBatchContext(ctx context.Context, batchType BatchType) error {
 	if err := mo.Preflight(); err != nil {
 		return err
 	}
-	stmts := make([]string, len(mo))
-	vals := make([][]interface{}, len(mo))
+	ops, err := mo.resolve(ctx)
+	if err != nil {
+		return err
+	}
+	stmts := make([]string, len(ops))
+	vals := make([][]interface{}, len(ops))
 	var qe QueryExecutor
-	for i, op := range mo {
+	for i, op := range ops {
+		if _, ok := op.(mockOp); ok {
+			// Mock ops have no statements, the mock keyspace runs them one after the other
+			return ops.RunContext(ctx)
+		}
+		if op.QueryExecutor() == nil {
+			return fmt.Errorf("%T can not be run in a batch", op)
+		}
+		if single, ok := op.(*singleOp); ok && single.cas != nil {
+			if len(ops) == 1 {
+				return single.RunContext(ctx)
+			}
+			// The outcome of conditional batches is not reported per statement, so it could not fill the results
+			return fmt.Errorf("Conditional writes can not be run in a batch with other ops")
+		}
 		s, v := op.GenerateStatement()
 		qe = op.QueryExecutor()
 		stmts[i] = s
 		vals[i] = v
 	}
+	if qe == nil {
+		// Noop
+		return nil
+	}
 
-	return qe.ExecuteAtomically(stmts, vals)
+	return qe.ExecuteBatch(ctx, batchType, stmts, vals)
+}
+
+// resolve returns the ops to batch, replacing the deferred ops by the ops they build. Only the deferred ops whose
+// writes are meant to be atomic can be batched, the others write or read as they are built.
+func (mo multiOp) resolve(ctx context.Context) (multiOp, error) {
+	ret := multiOp{}
+	for _, op := range mo {
+		deferred, ok := op.(*deferredOp)
+		if !ok {
+			ret = append(ret, op)
+			continue
+		}
+		if !deferred.atomic {
+			return nil, fmt.Errorf("Ops which read or write as they are run can not be run in a batch")
+		}
+		built, err := deferred.op(ctx)
+		if err != nil {
+			return nil, err
+		}
+		resolved, err := Noop().Add(built).(multiOp).resolve(ctx)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, resolved...)
+	}
+	return ret, nil
 }
 
 func (mo multiOp) GenerateStatement() (string, []interface{}) {
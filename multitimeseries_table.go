@@ -85,6 +85,22 @@ func (o *multiTimeSeriesT) Read(v interface{}, timeStamp time.Time, id, pointer
 }
 
 func (o *multiTimeSeriesT) List(v interface{}, startTime time.Time, endTime time.Time, pointerToASlice interface{}) Op {
+	f, err := o.list(v, startTime, endTime)
+	if err != nil {
+		return &badOp{err}
+	}
+	return f.Read(pointerToASlice)
+}
+
+func (o *multiTimeSeriesT) ListIter(v interface{}, startTime time.Time, endTime time.Time) Iterator {
+	f, err := o.list(v, startTime, endTime)
+	if err != nil {
+		return &badIterator{err}
+	}
+	return f.Iter()
+}
+
+func (o *multiTimeSeriesT) list(v interface{}, startTime time.Time, endTime time.Time) (Filter, error) {
 	buckets := []interface{}{}
 	start := o.bucket(startTime.Unix())
 	end := o.bucket(endTime.Unix())
@@ -93,10 +109,10 @@ func (o *multiTimeSeriesT) List(v interface{}, startTime time.Time, endTime time
 	}
 	idxs, err := o.indexes(v)
 	if err != nil {
-		return &badOp{err}
+		return nil, err
 	}
 	relations := fRelations(idxs, In(bucketFieldName, buckets...), GTE(o.timeField, startTime), LTE(o.timeField, endTime))
-	return o.Where(relations...).Read(pointerToASlice)
+	return o.Where(relations...), nil
 }
 
 func (o *multiTimeSeriesT) WithOptions(opt Options) MultiTimeSeriesTable {
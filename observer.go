@@ -0,0 +1,198 @@
+package gocassa
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// QueryObserver is called after every statement executed through an observed QueryExecutor, see
+// ObserveQueryExecutor. It is called synchronously, so it should not block.
+type QueryObserver func(ctx context.Context, e QueryEvent)
+
+// QueryEvent describes a statement (or a batch of statements) executed by a QueryExecutor
+type QueryEvent struct {
+	// Statement and Params are the executed statement and its parameters, they are empty for batches
+	Statement string
+	Params    []interface{}
+	// Batch holds the statements of a batch, it is nil for single statements
+	Batch *BatchEvent
+	// Options are the options the statement was executed with
+	Options Options
+	// Latency is the time the statement took. For iterators it is the time until the iterator was closed.
+	Latency time.Duration
+	// Rows is the number of rows returned by a query
+	Rows int
+	// Err is the error the statement failed with, if any
+	Err error
+}
+
+// BatchEvent describes the statements of a batch
+type BatchEvent struct {
+	Type       BatchType
+	Statements []string
+	Params     [][]interface{}
+}
+
+// ObserveQueryExecutor wraps a QueryExecutor so the observers are called after every statement it executes, in the
+// order they are given. Wrapping an observed QueryExecutor again adds the observers to the chain.
+func ObserveQueryExecutor(qe QueryExecutor, observers ...QueryObserver) QueryExecutor {
+	if o, ok := qe.(observedQE); ok {
+		chain := make([]QueryObserver, 0, len(o.observers)+len(observers))
+		chain = append(chain, o.observers...)
+		return observedQE{
+			qe:        o.qe,
+			observers: append(chain, observers...),
+		}
+	}
+	return observedQE{
+		qe:        qe,
+		observers: observers,
+	}
+}
+
+// debugLogger prints the statements to stdout, it is the observer of keyspaces in debug mode
+func debugLogger(ctx context.Context, e QueryEvent) {
+	if e.Batch == nil {
+		fmt.Println(e.Statement, e.Params)
+		return
+	}
+	for i := range e.Batch.Statements {
+		fmt.Println(e.Batch.Statements[i], e.Batch.Params[i])
+	}
+}
+
+type observedQE struct {
+	qe        QueryExecutor
+	observers []QueryObserver
+}
+
+func (o observedQE) observe(ctx context.Context, start time.Time, e QueryEvent) {
+	e.Latency = time.Since(start)
+	for _, observer := range o.observers {
+		observer(ctx, e)
+	}
+}
+
+func (o observedQE) QueryWithOptions(opts Options, stmt string, params ...interface{}) ([]map[string]interface{}, error) {
+	start := time.Now()
+	rows, err := o.qe.QueryWithOptions(opts, stmt, params...)
+	o.observe(context.Background(), start, QueryEvent{Statement: stmt, Params: params, Options: opts, Rows: len(rows), Err: err})
+	return rows, err
+}
+
+func (o observedQE) Query(stmt string, params ...interface{}) ([]map[string]interface{}, error) {
+	start := time.Now()
+	rows, err := o.qe.Query(stmt, params...)
+	o.observe(context.Background(), start, QueryEvent{Statement: stmt, Params: params, Rows: len(rows), Err: err})
+	return rows, err
+}
+
+func (o observedQE) QueryContext(ctx context.Context, opts Options, stmt string, params ...interface{}) ([]map[string]interface{}, error) {
+	start := time.Now()
+	rows, err := o.qe.QueryContext(ctx, opts, stmt, params...)
+	o.observe(ctx, start, QueryEvent{Statement: stmt, Params: params, Options: opts, Rows: len(rows), Err: err})
+	return rows, err
+}
+
+func (o observedQE) QueryIter(ctx context.Context, opts Options, stmt string, params ...interface{}) RowIterator {
+	return &observedRowIterator{
+		RowIterator: o.qe.QueryIter(ctx, opts, stmt, params...),
+		ctx:         ctx,
+		qe:          o,
+		start:       time.Now(),
+		event:       QueryEvent{Statement: stmt, Params: params, Options: opts},
+	}
+}
+
+func (o observedQE) ExecuteWithOptions(opts Options, stmt string, params ...interface{}) error {
+	start := time.Now()
+	err := o.qe.ExecuteWithOptions(opts, stmt, params...)
+	o.observe(context.Background(), start, QueryEvent{Statement: stmt, Params: params, Options: opts, Err: err})
+	return err
+}
+
+func (o observedQE) Execute(stmt string, params ...interface{}) error {
+	start := time.Now()
+	err := o.qe.Execute(stmt, params...)
+	o.observe(context.Background(), start, QueryEvent{Statement: stmt, Params: params, Err: err})
+	return err
+}
+
+func (o observedQE) ExecuteContext(ctx context.Context, opts Options, stmt string, params ...interface{}) error {
+	start := time.Now()
+	err := o.qe.ExecuteContext(ctx, opts, stmt, params...)
+	o.observe(ctx, start, QueryEvent{Statement: stmt, Params: params, Options: opts, Err: err})
+	return err
+}
+
+func (o observedQE) ExecuteCAS(ctx context.Context, opts Options, current map[string]interface{}, stmt string, params ...interface{}) (bool, error) {
+	start := time.Now()
+	applied, err := o.qe.ExecuteCAS(ctx, opts, current, stmt, params...)
+	o.observe(ctx, start, QueryEvent{Statement: stmt, Params: params, Options: opts, Err: err})
+	return applied, err
+}
+
+func (o observedQE) ExecuteAtomically(stmts []string, params [][]interface{}) error {
+	start := time.Now()
+	err := o.qe.ExecuteAtomically(stmts, params)
+	o.observe(context.Background(), start, batchEvent(LoggedBatch, stmts, params, err))
+	return err
+}
+
+func (o observedQE) ExecuteAtomicallyContext(ctx context.Context, stmts []string, params [][]interface{}) error {
+	start := time.Now()
+	err := o.qe.ExecuteAtomicallyContext(ctx, stmts, params)
+	o.observe(ctx, start, batchEvent(LoggedBatch, stmts, params, err))
+	return err
+}
+
+func (o observedQE) ExecuteBatch(ctx context.Context, batchType BatchType, stmts []string, params [][]interface{}) error {
+	start := time.Now()
+	err := o.qe.ExecuteBatch(ctx, batchType, stmts, params)
+	o.observe(ctx, start, batchEvent(batchType, stmts, params, err))
+	return err
+}
+
+func (o observedQE) Close() {
+	o.qe.Close()
+}
+
+func batchEvent(batchType BatchType, stmts []string, params [][]interface{}, err error) QueryEvent {
+	return QueryEvent{
+		Batch: &BatchEvent{
+			Type:       batchType,
+			Statements: stmts,
+			Params:     params,
+		},
+		Err: err,
+	}
+}
+
+// observedRowIterator counts the rows and reports the query once closed
+type observedRowIterator struct {
+	RowIterator
+	ctx    context.Context
+	qe     observedQE
+	start  time.Time
+	event  QueryEvent
+	closed bool
+}
+
+func (it *observedRowIterator) MapScan(m map[string]interface{}) bool {
+	if !it.RowIterator.MapScan(m) {
+		return false
+	}
+	it.event.Rows++
+	return true
+}
+
+func (it *observedRowIterator) Close() error {
+	err := it.RowIterator.Close()
+	if !it.closed {
+		it.closed = true
+		it.event.Err = err
+		it.qe.observe(it.ctx, it.start, it.event)
+	}
+	return err
+}
@@ -0,0 +1,98 @@
+package gocassa
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Mock QueryExecutor returning the same rows for every query
+type RowsQE struct {
+	OptionCheckingQE
+	rows []map[string]interface{}
+}
+
+func (qe RowsQE) QueryContext(ctx context.Context, opts Options, stmt string, params ...interface{}) ([]map[string]interface{}, error) {
+	return qe.rows, nil
+}
+
+func (qe RowsQE) Query(stmt string, params ...interface{}) ([]map[string]interface{}, error) {
+	return qe.rows, nil
+}
+
+func (qe RowsQE) QueryIter(ctx context.Context, opts Options, stmt string, params ...interface{}) RowIterator {
+	return &sliceRowIterator{rows: qe.rows}
+}
+
+func (qe RowsQE) ExecuteContext(ctx context.Context, opts Options, stmt string, params ...interface{}) error {
+	return errors.New("write failed")
+}
+
+func TestQueryObserver(t *testing.T) {
+	var events []QueryEvent
+	observer := func(ctx context.Context, e QueryEvent) {
+		events = append(events, e)
+	}
+	qe := RowsQE{
+		OptionCheckingQE: OptionCheckingQE{opts: &Options{}},
+		rows:             []map[string]interface{}{{"id": "1"}, {"id": "2"}},
+	}
+	ks := NewConnection(qe).WithQueryObserver(observer).KeySpace("ks")
+	cs := ks.Table("customer", Customer{}, Keys{PartitionKeys: []string{"Id"}}).WithOptions(Options{
+		TableName: "customer",
+	})
+
+	var customers []Customer
+	if err := cs.Where(Eq("Id", "1")).Read(&customers).WithOptions(Options{Limit: 10}).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event but got %v", events)
+	}
+	e := events[0]
+	if !strings.HasPrefix(e.Statement, "SELECT ") || !strings.HasSuffix(e.Statement, "WHERE id = ? LIMIT ?") || e.Rows != 2 || e.Options.Limit != 10 || e.Err != nil {
+		t.Fatalf("Unexpected event %+v", e)
+	}
+
+	// Iterators are reported once closed
+	it := cs.Where(In("Id", "1", "2")).Iter()
+	var c Customer
+	for it.Next(&c) {
+		if len(events) != 1 {
+			t.Fatal("Iterator reported before being closed")
+		}
+	}
+	if len(events) != 2 || events[1].Rows != 2 {
+		t.Fatalf("Unexpected events %+v", events)
+	}
+
+	err := cs.Set(Customer{Id: "1", Name: "Joe"}).Run()
+	if len(events) != 3 || events[2].Err != err || err == nil || len(events[2].Params) != 2 {
+		t.Fatalf("Unexpected events %+v", events)
+	}
+
+	// Observers of a keyspace are chained after the ones of its connection
+	var keySpaceEvents []QueryEvent
+	ks = ks.WithQueryObserver(func(ctx context.Context, e QueryEvent) {
+		if len(events) != 4 {
+			t.Fatal("Keyspace observer called before the connection one")
+		}
+		keySpaceEvents = append(keySpaceEvents, e)
+	})
+	cs = ks.Table("customer", Customer{}, Keys{PartitionKeys: []string{"Id"}}).WithOptions(Options{
+		TableName: "customer",
+	})
+	op := cs.Where(Eq("Id", "1")).Delete().Add(cs.Where(Eq("Id", "2")).Delete())
+	if err := op.RunBatch(UnloggedBatch); err != nil {
+		t.Fatal(err)
+	}
+	if len(keySpaceEvents) != 1 {
+		t.Fatalf("Expected 1 event but got %v", keySpaceEvents)
+	}
+	e = keySpaceEvents[0]
+	if e.Statement != "" || e.Batch == nil || e.Batch.Type != UnloggedBatch || len(e.Batch.Statements) != 2 ||
+		e.Batch.Statements[0] != "DELETE FROM ks.customer WHERE id = ?" || e.Batch.Params[1][0] != "2" {
+		t.Fatalf("Unexpected event %+v", e)
+	}
+}
@@ -2,13 +2,17 @@ package gocassa
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"math"
 	"math/big"
 	"reflect"
 	"runtime"
-	"strconv"
+	"strings"
+	"time"
 
 	rreflect "github.com/gocassa/gocassa/reflect"
+	"github.com/gocql/gocql"
 	"github.com/mitchellh/mapstructure"
 )
 
@@ -27,6 +31,7 @@ type singleOp struct {
 	result  interface{}
 	m       map[string]interface{} // map for updates, sets etc
 	qe      QueryExecutor
+	cas     *casCondition // condition for lightweight transactions, nil otherwise
 }
 
 // Used to pass errors back through the fluent API
@@ -41,14 +46,22 @@ func (o *singleOp) WithOptions(opts Options) Op {
 		opType:  o.opType,
 		result:  o.result,
 		m:       o.m,
-		qe:      o.qe}
+		qe:      o.qe,
+		cas:     o.cas}
 }
 
 func (o *singleOp) Add(additions ...Op) Op {
 	return multiOp{o}.Add(additions...)
 }
 
+// Preflight rejects TTLs which round to zero seconds, as Cassandra would never expire the cells instead
 func (o *singleOp) Preflight() error {
+	if o.opType != insertOpType && o.opType != updateOpType {
+		return nil
+	}
+	if ttl := o.f.t.options.Merge(o.options).TTL; ttl != 0 && ttlSeconds(ttl) == 0 {
+		return fmt.Errorf("TTL %s rounds to zero seconds, which would never expire", ttl)
+	}
 	return nil
 }
 
@@ -60,9 +73,9 @@ func newWriteOp(qe QueryExecutor, f filter, opType uint8, m map[string]interface
 		m:      m}
 }
 
-func (w *singleOp) read() error {
+func (w *singleOp) read(ctx context.Context) error {
 	stmt, params := w.generateRead(w.options)
-	maps, err := w.qe.QueryWithOptions(w.options, stmt, params...)
+	maps, err := w.qe.QueryContext(ctx, w.options, stmt, params...)
 	if err != nil {
 		return err
 	}
@@ -70,14 +83,20 @@ func (w *singleOp) read() error {
 	return decodeResult(maps, w.result)
 }
 
-func (w *singleOp) readOne() error {
+func (w *singleOp) iter(ctx context.Context) Iterator {
+	stmt, params := w.generateRead(w.options)
+	return newIterator(w.qe.QueryIter(ctx, w.options, stmt, params...))
+}
+
+func (w *singleOp) readOne(ctx context.Context) error {
 	stmt, params := w.generateRead(w.options)
-	maps, err := w.qe.QueryWithOptions(w.options, stmt, params...)
+	maps, err := w.qe.QueryContext(ctx, w.options, stmt, params...)
 	if err != nil {
 		return err
 	}
 	if len(maps) == 0 {
-		_, f, n, _ := runtime.Caller(3)
+		// The entry points all call run, so the depth of their caller does not depend on which one was called
+		_, f, n, _ := runtime.Caller(4)
 		return RowNotFoundError{
 			file: f,
 			line: n,
@@ -86,27 +105,67 @@ func (w *singleOp) readOne() error {
 	return decodeResult(maps[0], w.result)
 }
 
-func (w *singleOp) write() error {
+func (w *singleOp) write(ctx context.Context) error {
+	if err := w.Preflight(); err != nil {
+		return err
+	}
 	stmt, params := w.generateWrite(w.options)
-	return w.qe.ExecuteWithOptions(w.options, stmt, params...)
+	if w.cas != nil {
+		current := map[string]interface{}{}
+		applied, err := w.qe.ExecuteCAS(ctx, w.options, current, stmt, params...)
+		if err != nil {
+			return err
+		}
+		w.cas.setResult(applied, current)
+		return nil
+	}
+	return w.qe.ExecuteContext(ctx, w.options, stmt, params...)
 }
 
 func (o *singleOp) Run() error {
+	return o.run(context.Background())
+}
+
+func (o *singleOp) RunContext(ctx context.Context) error {
+	return o.run(ctx)
+}
+
+func (o *singleOp) run(ctx context.Context) error {
 	switch o.opType {
 	case updateOpType, insertOpType, deleteOpType:
-		return o.write()
+		return o.write(ctx)
 	case readOpType:
-		return o.read()
+		return o.read(ctx)
 	case singleReadOpType:
-		return o.readOne()
+		return o.readOne(ctx)
 	}
 	return nil
 }
 
 func (o *singleOp) RunAtomically() error {
+	return o.run(context.Background())
+}
+
+func (o *singleOp) RunAtomicallyContext(ctx context.Context) error {
+	return o.run(ctx)
+}
+
+func (o *singleOp) RunBatch(batchType BatchType) error {
+	return o.Run()
+}
+
+func (o *singleOp) RunBatchContext(ctx context.Context, batchType BatchType) error {
+	return o.RunContext(ctx)
+}
+
+func (o *singleOp) RunParallel(concurrency int) error {
 	return o.Run()
 }
 
+func (o *singleOp) RunParallelContext(ctx context.Context, concurrency int) error {
+	return o.RunContext(ctx)
+}
+
 func (o *singleOp) GenerateStatement() (string, []interface{}) {
 	switch o.opType {
 	case updateOpType, insertOpType, deleteOpType:
@@ -139,10 +198,34 @@ func (o *badOp) Run() error {
 	return o.err
 }
 
+func (o *badOp) RunContext(ctx context.Context) error {
+	return o.err
+}
+
 func (o *badOp) RunAtomically() error {
 	return o.Run()
 }
 
+func (o *badOp) RunAtomicallyContext(ctx context.Context) error {
+	return o.Run()
+}
+
+func (o *badOp) RunBatch(batchType BatchType) error {
+	return o.Run()
+}
+
+func (o *badOp) RunBatchContext(ctx context.Context, batchType BatchType) error {
+	return o.Run()
+}
+
+func (o *badOp) RunParallel(concurrency int) error {
+	return o.Run()
+}
+
+func (o *badOp) RunParallelContext(ctx context.Context, concurrency int) error {
+	return o.Run()
+}
+
 func (o *badOp) GenerateStatement() (string, []interface{}) {
 	return "", []interface{}{}
 }
@@ -153,61 +236,129 @@ func (o *badOp) QueryExecutor() QueryExecutor {
 
 //////
 
+// generateWrite only collects the values of the statement and its shape, the text of the statement is only built
+// the first time a shape is seen on the table
 func (o *singleOp) generateWrite(opt Options) (string, []interface{}) {
+	mopt := o.f.t.options.Merge(opt)
+	if o.cas != nil {
+		// Cassandra rejects custom timestamps for conditional writes, which get the timestamp of their transaction
+		mopt.Timestamp = time.Time{}
+	}
+	shape := newStatementShape(o.opType, o.f.t.keySpace.name, o.f.t.Name())
+	var fields []string
+	vals := []interface{}{}
+	switch o.opType {
+	case updateOpType:
+		fields = sortedKeys(o.m)
+		shape.using(mopt, true)
+		vals = append(vals, usingValues(mopt, true)...)
+		for _, k := range fields {
+			if mod, ok := o.m[k].(Modifier); ok {
+				stmt, mvals := mod.cql(k)
+				shape.add(stmt)
+				vals = append(vals, mvals...)
+				continue
+			}
+			shape.add(k)
+			vals = append(vals, o.f.t.info.columnValue(k, o.m[k]))
+		}
+		shape.relations(o.f.rs)
+		vals = append(vals, relationValues(o.f.rs)...)
+	case deleteOpType:
+		shape.using(mopt, false)
+		vals = append(vals, usingValues(mopt, false)...)
+		shape.relations(o.f.rs)
+		vals = append(vals, relationValues(o.f.rs)...)
+	case insertOpType:
+		fields = sortedKeys(o.m)
+		for _, k := range fields {
+			shape.add(k)
+			vals = append(vals, o.f.t.info.columnValue(k, o.m[k]))
+		}
+		shape.using(mopt, true)
+		vals = append(vals, usingValues(mopt, true)...)
+	}
+	shape.cas(o.cas)
+	if o.cas != nil && o.opType != insertOpType {
+		vals = append(vals, relationValues(o.cas.conditions)...)
+	}
+	for i, v := range vals {
+		vals[i] = udtValue(v)
+	}
+
+	stmt := o.f.t.info.statements.get(shape.Bytes(), func() string {
+		return o.buildWrite(fields, mopt)
+	})
+	return stmt, vals
+}
+
+func (o *singleOp) buildWrite(fields []string, opt Options) string {
 	var str string
-	var vals []interface{}
 	switch o.opType {
 	case updateOpType:
-		stmt, uvals := updateStatement(o.f.t.keySpace.name, o.f.t.Name(), o.m, o.f.t.options.Merge(opt))
-		whereStmt, whereVals := generateWhere(o.f.rs)
-		str = stmt + whereStmt
-		vals = append(uvals, whereVals...)
+		whereStmt, _ := generateWhere(o.f.rs)
+		str = updateStatement(o.f.t.keySpace.name, o.f.t.Name(), fields, o.m, opt) + whereStmt
 	case deleteOpType:
-		str, vals = generateWhere(o.f.rs)
+		str, _ = generateWhere(o.f.rs)
+		if using := usingStatement(opt, false); using != "" {
+			str = " " + using + str
+		}
 		str = fmt.Sprintf("DELETE FROM %s.%s%s", o.f.t.keySpace.name, o.f.t.Name(), str)
 	case insertOpType:
-		fields, insertVals := keyValues(o.m)
-		str = insertStatement(o.f.t.keySpace.name, o.f.t.Name(), fields, o.f.t.options.Merge(opt))
-		vals = insertVals
+		str = insertStatement(o.f.t.keySpace.name, o.f.t.Name(), fields, o.cas != nil && o.cas.notExists, opt)
 	}
-	if o.f.t.keySpace.debugMode {
-		fmt.Println(str, vals)
+	if o.cas != nil && o.opType != insertOpType {
+		ifStmt, _ := o.cas.cql()
+		str += ifStmt
 	}
-	return str, vals
+	return str
 }
 
+// generateRead only collects the values of the statement and its shape, like generateWrite
 func (o *singleOp) generateRead(opt Options) (string, []interface{}) {
-	w, wv := generateWhere(o.f.rs)
 	mopt := o.f.t.options.Merge(opt)
-	ord, ov := o.generateOrderBy(mopt)
-	lim, lv := o.generateLimit(mopt)
+	shape := newStatementShape(readOpType, o.f.t.keySpace.name, o.f.t.Name())
+	shape.add(mopt.Select...)
+	shape.relations(o.f.rs)
+	for _, co := range mopt.ClusteringOrder {
+		shape.add(co.Column, co.Direction.String())
+	}
+	shape.flag(mopt.Limit > 0)
+	shape.flag(opt.AllowFiltering)
+
+	vals := relationValues(o.f.rs)
+	_, lv := o.generateLimit(mopt)
+	vals = append(vals, lv...)
+
+	stmt := o.f.t.info.statements.get(shape.Bytes(), func() string {
+		return o.buildRead(opt)
+	})
+	return stmt, vals
+}
+
+func (o *singleOp) buildRead(opt Options) string {
+	w, _ := generateWhere(o.f.rs)
+	mopt := o.f.t.options.Merge(opt)
+	ord, _ := o.generateOrderBy(mopt)
+	lim, _ := o.generateLimit(mopt)
 	stmt := fmt.Sprintf("SELECT %s FROM %s.%s", o.f.t.generateFieldNames(mopt.Select), o.f.t.keySpace.name, o.f.t.Name())
-	vals := []interface{}{}
 	buf := new(bytes.Buffer)
 	buf.WriteString(stmt)
-	if w != "" {
-		buf.WriteString(" ")
-		buf.WriteString(w)
-		vals = append(vals, wv...)
-	}
+	// The where clause starts with a space
+	buf.WriteString(w)
 	if ord != "" {
 		buf.WriteString(" ")
 		buf.WriteString(ord)
-		vals = append(vals, ov...)
 	}
 	if lim != "" {
 		buf.WriteString(" ")
 		buf.WriteString(lim)
-		vals = append(vals, lv...)
 	}
 	if opt.AllowFiltering {
 		buf.WriteString(" ")
 		buf.WriteString("ALLOW FILTERING")
 	}
-	if o.f.t.keySpace.debugMode {
-		fmt.Println(buf.String(), vals)
-	}
-	return buf.String(), vals
+	return buf.String()
 }
 
 func (o *singleOp) generateOrderBy(opt Options) (string, []interface{}) {
@@ -237,60 +388,80 @@ func (o *singleOp) generateLimit(opt Options) (string, []interface{}) {
 }
 
 func generateWhere(rs []Relation) (string, []interface{}) {
-	var (
-		vals []interface{}
-		buf  = new(bytes.Buffer)
-	)
-
+	buf := new(bytes.Buffer)
 	if len(rs) > 0 {
 		buf.WriteString(" WHERE ")
 		for i, r := range rs {
 			if i > 0 {
 				buf.WriteString(" AND ")
 			}
-			s, v := r.cql()
+			s, _ := r.cql()
 			buf.WriteString(s)
-			if r.op == in {
-				vals = append(vals, v)
-				continue
-			}
-			vals = append(vals, v...)
 		}
 	}
-	return buf.String(), vals
+	return buf.String(), relationValues(rs)
 }
 
-// UPDATE keyspace.Movies SET col1 = val1, col2 = val2
-func updateStatement(kn, cfName string, fields map[string]interface{}, opts Options) (string, []interface{}) {
+// UPDATE keyspace.Movies SET col1 = ?, col2 = ?
+// The fields are given in the order they should appear in.
+func updateStatement(kn, cfName string, fieldNames []string, fields map[string]interface{}, opts Options) string {
 	buf := new(bytes.Buffer)
 	buf.WriteString(fmt.Sprintf("UPDATE %s.%s ", kn, cfName))
 
 	// Apply options
-	if opts.TTL != 0 {
-		buf.WriteString("USING TTL ")
-		buf.WriteString(strconv.FormatFloat(opts.TTL.Seconds(), 'f', 0, 64))
+	if using := usingStatement(opts, true); using != "" {
+		buf.WriteString(using)
 		buf.WriteRune(' ')
 	}
 
 	buf.WriteString("SET ")
-	i := 0
-	ret := []interface{}{}
-	for k, v := range fields {
+	for i, k := range fieldNames {
 		if i > 0 {
 			buf.WriteString(", ")
 		}
-		if mod, ok := v.(Modifier); ok {
-			stmt, vals := mod.cql(k)
+		if mod, ok := fields[k].(Modifier); ok {
+			stmt, _ := mod.cql(k)
 			buf.WriteString(stmt)
-			ret = append(ret, vals...)
 		} else {
 			buf.WriteString(k + " = ?")
-			ret = append(ret, v)
 		}
-		i++
 	}
 
-	return buf.String(), ret
+	return buf.String()
+}
+
+// USING TTL ? AND TIMESTAMP ?
+// TTL is only applied if allowed, as deletes do not accept it. Both are bound rather than inlined, so writes with
+// different timestamps share the same statement.
+func usingStatement(opts Options, ttl bool) string {
+	parts := []string{}
+	if ttl && opts.TTL != 0 {
+		parts = append(parts, "TTL ?")
+	}
+	if !opts.Timestamp.IsZero() {
+		parts = append(parts, "TIMESTAMP ?")
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "USING " + strings.Join(parts, " AND ")
+}
+
+// usingValues returns the values bound by usingStatement: the TTL in seconds and the timestamp in microseconds
+func usingValues(opts Options, ttl bool) []interface{} {
+	vals := []interface{}{}
+	if ttl && opts.TTL != 0 {
+		vals = append(vals, ttlSeconds(opts.TTL))
+	}
+	if !opts.Timestamp.IsZero() {
+		vals = append(vals, opts.Timestamp.UnixNano()/int64(time.Microsecond))
+	}
+	return vals
+}
+
+// ttlSeconds returns the TTL rounded to the second, the precision of Cassandra TTLs
+func ttlSeconds(ttl time.Duration) int {
+	return int(math.RoundToEven(ttl.Seconds()))
 }
 
 func decodeResult(m, result interface{}) error {
@@ -301,6 +472,8 @@ func decodeResult(m, result interface{}) error {
 		TagName:          rreflect.TagName,
 		DecodeHook: mapstructure.ComposeDecodeHookFunc(
 			decodeBigIntHook,
+			decodeDurationHook,
+			decodeTupleHook,
 		),
 	})
 	if err != nil {
@@ -336,3 +509,15 @@ func decodeBigIntHook(f reflect.Kind, t reflect.Kind, data interface{}) (interfa
 
 	return data, nil
 }
+
+// decodeDurationHook decodes duration columns, which gocql reads as gocql.Duration, into time.Duration fields
+func decodeDurationHook(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+	d, ok := data.(gocql.Duration)
+	if !ok || t != reflect.TypeOf(time.Duration(0)) {
+		return data, nil
+	}
+	if d.Months != 0 {
+		return nil, fmt.Errorf("Can not decode a duration of %d months into a time.Duration", d.Months)
+	}
+	return time.Duration(d.Days)*24*time.Hour + time.Duration(d.Nanoseconds), nil
+}
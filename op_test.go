@@ -1,7 +1,9 @@
 package gocassa
 
 import (
+	"context"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -95,3 +97,21 @@ func TestDecodeSingleRow(t *testing.T) {
 		t.Fatalf("Did not get expected result")
 	}
 }
+
+func readMissingRow(op Op, withContext bool) error {
+	if withContext {
+		return op.RunContext(context.Background())
+	}
+	return op.Run()
+}
+
+func TestRowNotFoundErrorCaller(t *testing.T) {
+	ks := NewConnection(OptionCheckingQE{opts: &Options{}}).KeySpace("ks")
+	u := user{}
+	op := ks.MapTable("users", "Pk1", user{}).Read(1, &u)
+	// The error reports the same caller whether the op was run with or without a context
+	errRun, errContext := readMissingRow(op, false), readMissingRow(op, true)
+	if _, ok := errRun.(RowNotFoundError); !ok || errRun != errContext || !strings.HasPrefix(errRun.Error(), "op_test.go:") {
+		t.Fatal(errRun, errContext)
+	}
+}
@@ -1,6 +1,9 @@
 package gocassa
 
 import (
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/gocql/gocql"
@@ -35,9 +38,13 @@ type ClusteringOrderColumn struct {
 // The reason for this is because statement specific (TTL, Limit) options make sense as table level options
 // (eg. have default TTL for every Update without specifying it all the time)
 type Options struct {
-	// TTL specifies a duration over which data is valid. It will be truncated to second precision upon statement
-	// execution.
+	// TTL specifies a duration over which data is valid. It will be rounded to second precision upon statement
+	// execution, writes whose TTL rounds to zero fail rather than never expiring.
 	TTL time.Duration
+	// Timestamp sets the write time of the cells touched by inserts, updates and deletes (USING TIMESTAMP), which
+	// Cassandra uses to resolve conflicting writes. It will be truncated to microsecond precision. If zero, the
+	// coordinator sets the write time. It is ignored by conditional writes, which Cassandra does not allow to set it.
+	Timestamp time.Time
 	// Limit query result set
 	Limit int
 	// TableName overrides the default internal table name. When naming a table 'users' the internal table name becomes 'users_someTableSpecificMetaInformation'.
@@ -52,24 +59,126 @@ type Options struct {
 	Consistency *gocql.Consistency
 	// Setting CompactStorage to true enables table creation with compact storage
 	CompactStorage bool
-	// Compressor specifies the compressor (if any) to use on a newly created table
+	// Compressor specifies the compressor (if any) to use on a newly created table. Properties can set it as well,
+	// along with the other compression options.
 	Compressor string
+	// Properties specifies the properties of newly created tables, see TableProperties. If nil, the defaults of
+	// Cassandra are used.
+	Properties *TableProperties
+	// Paging makes a read return only a single page of results, see Paging. If nil, results are not paged. As the
+	// state of the next page is written back into it, it is shared by every read made with these options: set it on
+	// a single read op with WithOptions, never on a table, and do not run concurrent reads with the same Paging.
+	Paging *Paging
+}
+
+// Replication strategies of KeySpaceOptions
+const (
+	SimpleStrategy          = "SimpleStrategy"
+	NetworkTopologyStrategy = "NetworkTopologyStrategy"
+)
+
+// KeySpaceOptions specify the replication of a keyspace. When creating a keyspace, the zero value is SimpleStrategy
+// with a replication factor of 1, which is mostly useful for tests. AlterKeySpace does not take the zero value.
+// Production keyspaces usually replicate to each of their datacenters, eg.
+//
+//	KeySpaceOptions{DatacenterRF: map[string]int{"eu-west-1": 3, "us-east-1": 3}}
+type KeySpaceOptions struct {
+	// Strategy is the replication strategy. If empty, it is NetworkTopologyStrategy if DatacenterRF is set and
+	// SimpleStrategy otherwise.
+	Strategy string
+	// ReplicationFactor is the number of replicas with SimpleStrategy, 1 if zero
+	ReplicationFactor int
+	// DatacenterRF is the number of replicas in each datacenter with NetworkTopologyStrategy
+	DatacenterRF map[string]int
+	// DurableWrites sets whether writes go through the commit log. If nil, it is omitted and Cassandra defaults to
+	// true.
+	DurableWrites *bool
+}
+
+// replication returns the replication map of the keyspace
+func (o KeySpaceOptions) replication() (string, error) {
+	strategy := o.Strategy
+	if strategy == "" {
+		strategy = SimpleStrategy
+		if len(o.DatacenterRF) > 0 {
+			strategy = NetworkTopologyStrategy
+		}
+	}
+	switch strategy {
+	case SimpleStrategy:
+		if len(o.DatacenterRF) > 0 {
+			return "", fmt.Errorf("%s does not take replication factors per datacenter", strategy)
+		}
+		rf := o.ReplicationFactor
+		if rf == 0 {
+			rf = 1
+		}
+		return fmt.Sprintf("{'class': '%s', 'replication_factor': %d}", strategy, rf), nil
+	case NetworkTopologyStrategy:
+		if len(o.DatacenterRF) == 0 {
+			return "", fmt.Errorf("%s needs the replication factor of at least one datacenter", strategy)
+		}
+		datacenters := make([]string, 0, len(o.DatacenterRF))
+		for dc := range o.DatacenterRF {
+			datacenters = append(datacenters, dc)
+		}
+		sort.Strings(datacenters)
+		entries := []string{fmt.Sprintf("'class': '%s'", strategy)}
+		for _, dc := range datacenters {
+			entries = append(entries, fmt.Sprintf("%s: %d", cqlString(dc), o.DatacenterRF[dc]))
+		}
+		return "{" + strings.Join(entries, ", ") + "}", nil
+	}
+	return "", fmt.Errorf("Unknown replication strategy %s", strategy)
+}
+
+// Paging is used to read a result set page by page. After a read, State holds an opaque cursor pointing to the
+// next page, which can be handed out to clients and fed back later to continue where the previous read left off.
+// Every read made with a Paging overwrites its State, so it should only be used by one read at a time.
+// For example:
+//
+//	paging := &Paging{PageSize: 100}
+//	for {
+//		if err := table.Where(Eq("Id", 1)).Read(&rows).WithOptions(Options{Paging: paging}).Run(); err != nil {
+//			return err
+//		}
+//		...
+//		if !paging.HasMore() {
+//			break
+//		}
+//	}
+type Paging struct {
+	// PageSize is the maximum number of rows returned by a read
+	PageSize int
+	// State is the cursor to continue from. Leave it empty to read the first page.
+	State []byte
+}
+
+// HasMore returns whether there are more pages to read after the last read.
+func (p *Paging) HasMore() bool {
+	return len(p.State) > 0
 }
 
 // Merge returns a new Options which is a right biased merge of the two initial Options.
 func (o Options) Merge(neu Options) Options {
 	ret := Options{
 		TTL:             o.TTL,
+		Timestamp:       o.Timestamp,
 		Limit:           o.Limit,
 		TableName:       o.TableName,
 		ClusteringOrder: o.ClusteringOrder,
 		Select:          o.Select,
 		CompactStorage:  o.CompactStorage,
 		Compressor:      o.Compressor,
+		Properties:      o.Properties,
+		Paging:          o.Paging,
 	}
 	if neu.TTL != time.Duration(0) {
 		ret.TTL = neu.TTL
 	}
+	if !neu.Timestamp.IsZero() {
+		ret.Timestamp = neu.Timestamp
+	}
 	if neu.Limit != 0 {
 		ret.Limit = neu.Limit
 	}
@@ -94,6 +203,16 @@ func (o Options) Merge(neu Options) Options {
 	if len(neu.Compressor) > 0 {
 		ret.Compressor = neu.Compressor
 	}
+	if neu.Properties != nil {
+		props := *neu.Properties
+		if ret.Properties != nil {
+			props = ret.Properties.Merge(props)
+		}
+		ret.Properties = &props
+	}
+	if neu.Paging != nil {
+		ret.Paging = neu.Paging
+	}
 	return ret
 }
 
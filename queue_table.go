@@ -0,0 +1,195 @@
+package gocassa
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+const (
+	queueShardField = "shard"
+	queueTimeField  = "enqueued"
+)
+
+// queueBucket is a row of the table indexing the buckets of each shard which may hold items
+type queueBucket struct {
+	Shard  int
+	Bucket int64
+}
+
+type queueT struct {
+	tableGroup
+	items      Table
+	buckets    Table
+	idField    string
+	shards     int
+	bucketSize time.Duration
+}
+
+func newQueueT(name, idField string, shards int, bucketSize time.Duration, items, buckets Table) *queueT {
+	return &queueT{
+		tableGroup: tableGroup{name: name, tables: []TableChanger{items.(TableChanger), buckets.(TableChanger)}},
+		items:      items,
+		buckets:    buckets,
+		idField:    idField,
+		shards:     shards,
+		bucketSize: bucketSize,
+	}
+}
+
+// bucket returns the bucket of the items enqueued at the given time, the same way time series tables do
+func (q *queueT) bucket(t time.Time) int64 {
+	return (&tsBucketer{bucketSize: q.bucketSize}).Bucket(t.Unix())
+}
+
+// shard returns the shard of the item, which spreads the items enqueued at the same time across partitions
+func (q *queueT) shard(id gocql.UUID) int {
+	h := fnv.New32a()
+	h.Write(id[:])
+	return int(h.Sum32() % uint32(q.shards))
+}
+
+// enqueuedAt returns the time the item was enqueued at, truncated to the precision of Cassandra timestamps
+func enqueuedAt(id gocql.UUID) time.Time {
+	return id.Time().Truncate(time.Millisecond)
+}
+
+func (q *queueT) itemID(v interface{}) (gocql.UUID, error) {
+	m, ok := toMap(v)
+	if !ok {
+		return gocql.UUID{}, fmt.Errorf("Can't convert %T to a map", v)
+	}
+	id, ok := m[q.idField].(gocql.UUID)
+	if !ok || id.Version() != 1 {
+		return gocql.UUID{}, fmt.Errorf("Field %s must be a time based gocql.UUID", q.idField)
+	}
+	return id, nil
+}
+
+func (q *queueT) Enqueue(v interface{}) Op {
+	m, ok := toMap(v)
+	if !ok {
+		return &badOp{fmt.Errorf("Can't convert %T to a map", v)}
+	}
+	id, err := q.itemID(m)
+	if err != nil {
+		return &badOp{err}
+	}
+	shard, tim := q.shard(id), enqueuedAt(id)
+	bucket := q.bucket(tim)
+	m[queueShardField] = shard
+	m[bucketFieldName] = bucket
+	m[queueTimeField] = tim
+	// The bucket is indexed first, so that an item is never written to a bucket which Peek does not know about. If
+	// writing the item then fails, the empty bucket is removed from the index by Peek once it is over.
+	return q.buckets.Set(queueBucket{Shard: shard, Bucket: bucket}).Add(q.items.Set(m))
+}
+
+// peekShard reads up to n of the oldest items of the shard into a new slice of the given type. It returns the
+// buckets which are over and hold no items anymore, as they can be removed from the index, and the time their items
+// were read at.
+func (q *queueT) peekShard(ctx context.Context, opts Options, shard, n int, sliceType reflect.Type) (reflect.Value, []int64, time.Time, error) {
+	ret := reflect.MakeSlice(sliceType, 0, n)
+	empty := []int64{}
+	buckets := []queueBucket{}
+	err := q.buckets.Where(Eq("Shard", shard)).Read(&buckets).WithOptions(opts).RunContext(ctx)
+	if err != nil {
+		return ret, nil, time.Time{}, err
+	}
+	readAt := time.Now()
+	// Items are only enqueued in the current bucket, keep a bucket of leeway for clock skew between the clients
+	over := q.bucket(time.Now().Add(-q.bucketSize))
+	for _, b := range buckets {
+		if ret.Len() >= n {
+			break
+		}
+		items := reflect.New(sliceType)
+		err := q.items.Where(Eq(queueShardField, shard), Eq(bucketFieldName, b.Bucket)).
+			Read(items.Interface()).
+			WithOptions(opts).
+			WithOptions(Options{Limit: n - ret.Len()}).
+			RunContext(ctx)
+		if err != nil {
+			return ret, nil, time.Time{}, err
+		}
+		if items.Elem().Len() == 0 && b.Bucket < over {
+			empty = append(empty, b.Bucket)
+		}
+		ret = reflect.AppendSlice(ret, items.Elem())
+	}
+	return ret, empty, readAt, nil
+}
+
+func (q *queueT) Peek(n int, pointerToASlice interface{}) Op {
+	ptr := reflect.ValueOf(pointerToASlice)
+	if ptr.Kind() != reflect.Ptr || ptr.Elem().Kind() != reflect.Slice {
+		return &badOp{fmt.Errorf("Peek needs a pointer to a slice, got %T", pointerToASlice)}
+	}
+	if n <= 0 {
+		return &badOp{fmt.Errorf("Peek needs a positive number of items, got %d", n)}
+	}
+	return newDeferredOp(false, func(ctx context.Context, opts Options) (Op, error) {
+		sliceType := ptr.Elem().Type()
+		items := reflect.MakeSlice(sliceType, 0, n)
+		cleanup := Noop()
+		for shard := 0; shard < q.shards; shard++ {
+			shardItems, empty, readAt, err := q.peekShard(ctx, opts, shard, n, sliceType)
+			if err != nil {
+				return nil, err
+			}
+			items = reflect.AppendSlice(items, shardItems)
+			// The buckets are removed as of the time they were read empty, so that indexing them again afterwards
+			// wins over the removal
+			for _, bucket := range empty {
+				cleanup = cleanup.Add(q.buckets.Where(Eq("Shard", shard), Eq("Bucket", bucket)).Delete().
+					WithOptions(Options{Timestamp: readAt}))
+			}
+		}
+		ids := make([]gocql.UUID, items.Len())
+		for i := range ids {
+			id, err := q.itemID(items.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			ids[i] = id
+		}
+		order := make([]int, len(ids))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			return ids[order[i]].Time().Before(ids[order[j]].Time())
+		})
+		if len(order) > n {
+			order = order[:n]
+		}
+		ret := reflect.MakeSlice(sliceType, len(order), len(order))
+		for i, idx := range order {
+			ret.Index(i).Set(items.Index(idx))
+		}
+		ptr.Elem().Set(ret)
+		return cleanup, nil
+	})
+}
+
+func (q *queueT) Ack(id gocql.UUID) Op {
+	if id.Version() != 1 {
+		return &badOp{fmt.Errorf("Item ids must be time based gocql.UUIDs, got version %d", id.Version())}
+	}
+	tim := enqueuedAt(id)
+	return q.items.Where(
+		Eq(queueShardField, q.shard(id)),
+		Eq(bucketFieldName, q.bucket(tim)),
+		Eq(queueTimeField, tim),
+		Eq(q.idField, id),
+	).Delete()
+}
+
+func (q *queueT) WithOptions(o Options) QueueTable {
+	return newQueueT(q.name, q.idField, q.shards, q.bucketSize, q.items.WithOptions(o), q.buckets.WithOptions(o))
+}
@@ -0,0 +1,103 @@
+package gocassa
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+type OutboxEvent struct {
+	Id    gocql.UUID
+	Topic string
+}
+
+func TestQueueTable(t *testing.T) {
+	tbl := ns.QueueTable("outbox", "Id", 4, time.Hour, OutboxEvent{})
+	createIf(tbl, t)
+	start := time.Date(2020, 2, 29, 10, 0, 0, 0, time.UTC)
+	events := make([]OutboxEvent, 6)
+	for i := range events {
+		events[i] = OutboxEvent{Id: gocql.UUIDFromTime(start.Add(time.Duration(i) * 40 * time.Minute)), Topic: "orders"}
+	}
+	// Enqueue them out of order
+	for _, i := range []int{3, 0, 5, 1, 4, 2} {
+		if err := tbl.Enqueue(events[i]).Run(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	peeked := []OutboxEvent{}
+	if err := tbl.Peek(3, &peeked).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if len(peeked) != 3 || peeked[0] != events[0] || peeked[1] != events[1] || peeked[2] != events[2] {
+		t.Fatal(peeked)
+	}
+	// Peeking does not remove the items
+	if err := tbl.Peek(10, &peeked).Run(); err != nil || len(peeked) != 6 {
+		t.Fatal(peeked, err)
+	}
+
+	for _, event := range events[:4] {
+		if err := tbl.Ack(event.Id).Run(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tbl.Peek(3, &peeked).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if len(peeked) != 2 || peeked[0] != events[4] || peeked[1] != events[5] {
+		t.Fatal(peeked)
+	}
+
+	for _, event := range events[4:] {
+		if err := tbl.Ack(event.Id).Run(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tbl.Peek(3, &peeked).Run(); err != nil || len(peeked) != 0 {
+		t.Fatal(peeked, err)
+	}
+	// The emptied buckets were removed from the index when peeked
+	buckets := []queueBucket{}
+	if err := tbl.(*queueT).buckets.Where(In("Shard", 0, 1, 2, 3)).Read(&buckets).Run(); err != nil || len(buckets) != 0 {
+		t.Fatal(buckets, err)
+	}
+}
+
+func TestQueueTableErrors(t *testing.T) {
+	tbl := ns.QueueTable("errors", "Id", 2, time.Hour, OutboxEvent{})
+	createIf(tbl, t)
+	id, err := gocql.RandomUUID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tbl.Enqueue(OutboxEvent{Id: id}).Run(); err == nil {
+		t.Fatal("Expected enqueueing an item without a time based id to fail")
+	}
+	if err := tbl.Ack(id).Run(); err == nil {
+		t.Fatal("Expected acknowledging an item without a time based id to fail")
+	}
+	peeked := []OutboxEvent{}
+	if err := tbl.Peek(0, &peeked).Run(); err == nil {
+		t.Fatal("Expected peeking no items to fail")
+	}
+	if err := tbl.Peek(1, peeked).Run(); err == nil {
+		t.Fatal("Expected peeking into a slice to fail")
+	}
+}
+
+func TestQueueTableEnqueueStatements(t *testing.T) {
+	stmts := []string{}
+	qe := StatementRecordingQE{OptionCheckingQE: OptionCheckingQE{opts: &Options{}}, stmts: &stmts}
+	tbl := NewConnection(qe).KeySpace("ks").QueueTable("outbox", "Id", 4, time.Hour, OutboxEvent{})
+	if err := tbl.Enqueue(OutboxEvent{Id: gocql.TimeUUID(), Topic: "orders"}).Run(); err != nil {
+		t.Fatal(err)
+	}
+	// The bucket is indexed before the item is written into it
+	if len(stmts) != 2 || !strings.Contains(stmts[0], "_buckets") || strings.Contains(stmts[1], "_buckets") {
+		t.Fatal(stmts)
+	}
+}
@@ -16,6 +16,9 @@ type field struct {
 	typ       reflect.Type
 	omitEmpty bool
 	quoted    bool
+	// metadata is set for fields holding the WRITETIME() or TTL() of a column, they are not columns themselves
+	metadata bool
+	options  tagOptions
 }
 
 func fillField(f field) field {
@@ -129,6 +132,8 @@ func typeFields(t reflect.Type) []field {
 						index:     index,
 						typ:       ft,
 						omitEmpty: opts.Contains("omitempty"),
+						metadata:  isMetadataTag(name),
+						options:   opts,
 					}))
 					if count[f.typ] > 1 {
 						// If there were multiple instances, add a second,
@@ -18,6 +18,8 @@ import (
 //
 //   // Field appears in the resulting map as key "myName"
 //   Field int "myName"
+//
+// Fields holding column metadata (see MetadataFields) are not part of the map.
 func StructToMap(val interface{}) (map[string]interface{}, bool) {
 	// indirect so function works with both structs and pointers to them
 	structVal := r.Indirect(r.ValueOf(val))
@@ -28,6 +30,9 @@ func StructToMap(val interface{}) (map[string]interface{}, bool) {
 	structFields := cachedTypeFields(structVal.Type())
 	mapVal := make(map[string]interface{}, len(structFields))
 	for _, info := range structFields {
+		if info.metadata {
+			continue
+		}
 		field := fieldByIndex(structVal, info.index)
 		mapVal[info.name] = field.Interface()
 	}
@@ -68,16 +73,73 @@ func FieldsAndValues(val interface{}) ([]string, []interface{}, bool) {
 		return nil, nil, false
 	}
 	structFields := cachedTypeFields(structVal.Type())
-	fields := make([]string, len(structFields))
-	values := make([]interface{}, len(structFields))
-	for i, info := range structFields {
+	fields := make([]string, 0, len(structFields))
+	values := make([]interface{}, 0, len(structFields))
+	for _, info := range structFields {
+		if info.metadata {
+			continue
+		}
 		field := fieldByIndex(structVal, info.index)
-		fields[i] = info.name
-		values[i] = field.Interface()
+		fields = append(fields, info.name)
+		values = append(values, field.Interface())
 	}
 	return fields, values, true
 }
 
+// TaggedField is a column of a struct along with the options of its tag, eg. "partition" for `cql:"id,partition"`
+type TaggedField struct {
+	Name    string
+	Options []string
+}
+
+// TaggedFields returns the columns of a struct in the order they are declared in, along with their tag options.
+// Fields holding column metadata are not part of them.
+func TaggedFields(val interface{}) []TaggedField {
+	typ := r.TypeOf(val)
+	for typ != nil && typ.Kind() == r.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != r.Struct {
+		return nil
+	}
+	fields := []TaggedField{}
+	for _, info := range cachedTypeFields(typ) {
+		if info.metadata {
+			continue
+		}
+		fields = append(fields, TaggedField{
+			Name:    info.name,
+			Options: info.options.list(),
+		})
+	}
+	return fields
+}
+
+// MetadataFields returns the names of the fields which hold the metadata of a column rather than a column, as read
+// by the WRITETIME() and TTL() functions of CQL. These are fields whose tag is named after the function call, eg.
+//
+//   // Field holds the write time of column "name" in microseconds
+//   NameWriteTime int64 `cql:"writetime(name)"`
+//
+//   // Field holds the remaining time to live of column "name" in seconds
+//   NameTTL int `cql:"ttl(name)"`
+func MetadataFields(val interface{}) []string {
+	typ := r.TypeOf(val)
+	for typ != nil && typ.Kind() == r.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != r.Struct {
+		return nil
+	}
+	fields := []string{}
+	for _, info := range cachedTypeFields(typ) {
+		if info.metadata {
+			fields = append(fields, info.name)
+		}
+	}
+	return fields
+}
+
 func fieldByIndex(v r.Value, index []int) r.Value {
 	for _, i := range index {
 		if v.Kind() == r.Ptr {
@@ -3,6 +3,7 @@ package reflect
 import (
 	"github.com/gocql/gocql"
 
+	r "reflect"
 	"testing"
 )
 
@@ -63,6 +64,59 @@ func TestStructToMap(t *testing.T) {
 	}
 }
 
+type TweetWithMetadata struct {
+	Tweet
+	TextWriteTime int64 `cql:"writetime(Text)"`
+	TextTTL       int   `cql:"ttl(Text)"`
+}
+
+func TestMetadataFields(t *testing.T) {
+	tweet := TweetWithMetadata{Tweet: Tweet{Text: "hello gocassa"}, TextWriteTime: 1}
+
+	m, _ := StructToMap(tweet)
+	if _, ok := m["writetime(Text)"]; ok {
+		t.Error("Metadata field should not be part of the map")
+	}
+	if m["Text"] != tweet.Text {
+		t.Errorf("Expected %s but got %s", tweet.Text, m["Text"])
+	}
+
+	fields, values, _ := FieldsAndValues(tweet)
+	if len(fields) != len(m) || len(values) != len(m) {
+		t.Errorf("Expected %d fields but got %v", len(m), fields)
+	}
+
+	meta := MetadataFields(&tweet)
+	if len(meta) != 2 || meta[0] != "writetime(Text)" || meta[1] != "ttl(Text)" {
+		t.Errorf("Unexpected metadata fields %v", meta)
+	}
+	if meta := MetadataFields(tweet.Tweet); len(meta) != 0 {
+		t.Errorf("Unexpected metadata fields %v", meta)
+	}
+}
+
+func TestTaggedFields(t *testing.T) {
+	type Event struct {
+		UserId  string `cql:"user_id,partition"`
+		Created int64  `cql:"created,clustering,desc"`
+		Payload string
+		Ignored string `cql:"-"`
+		TextTTL int    `cql:"ttl(Payload)"`
+	}
+	fields := TaggedFields(&Event{})
+	expected := []TaggedField{
+		{Name: "user_id", Options: []string{"partition"}},
+		{Name: "created", Options: []string{"clustering", "desc"}},
+		{Name: "Payload", Options: []string{}},
+	}
+	if !r.DeepEqual(fields, expected) {
+		t.Errorf("Expected %v but got %v", expected, fields)
+	}
+	if fields := TaggedFields(1); fields != nil {
+		t.Errorf("Unexpected fields %v", fields)
+	}
+}
+
 func TestMapToStruct(t *testing.T) {
 
 	m := make(map[string]interface{})
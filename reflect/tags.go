@@ -44,6 +44,24 @@ func isValidTag(s string) bool {
 	return true
 }
 
+// isMetadataTag returns whether the tag name refers to the metadata of a column, ie. writetime(column) or
+// ttl(column), which are the names Cassandra gives to the results of the WRITETIME() and TTL() functions.
+func isMetadataTag(s string) bool {
+	s = strings.ToLower(s)
+	if !strings.HasSuffix(s, ")") {
+		return false
+	}
+	return strings.HasPrefix(s, "writetime(") || strings.HasPrefix(s, "ttl(")
+}
+
+// list returns the options one by one
+func (o tagOptions) list() []string {
+	if len(o) == 0 {
+		return []string{}
+	}
+	return strings.Split(string(o), ",")
+}
+
 // Contains returns whether checks that a comma-separated list of options
 // contains a particular substr flag. substr must be surrounded by a
 // string boundary or commas.
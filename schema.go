@@ -0,0 +1,356 @@
+package gocassa
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The kinds of columns of a TableSchema
+const (
+	PartitionKeyColumn = "partition_key"
+	ClusteringColumn   = "clustering"
+	StaticColumn       = "static"
+	RegularColumn      = "regular"
+)
+
+// ColumnSchema describes a column of a live table
+type ColumnSchema struct {
+	Name string
+	// Type is the CQL type of the column, eg. "text" or "map<text, int>"
+	Type string
+	// Kind is one of PartitionKeyColumn, ClusteringColumn, StaticColumn or RegularColumn
+	Kind string
+}
+
+// TableSchema describes a live table, as read from the schema tables of Cassandra
+type TableSchema struct {
+	Name string
+	// Columns holds all the columns of the table, sorted by name
+	Columns           []ColumnSchema
+	PartitionKeys     []string
+	ClusteringColumns []string
+	ClusteringOrder   []ClusteringOrderColumn
+}
+
+// Column returns the column having the given name
+func (s TableSchema) Column(name string) (ColumnSchema, bool) {
+	for _, c := range s.Columns {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return ColumnSchema{}, false
+}
+
+// columnInfo is a column as found in the schema tables, before being assembled into a TableSchema
+type columnInfo struct {
+	ColumnSchema
+	Position   int
+	Descending bool
+}
+
+func newTableSchema(name string, columns []columnInfo) *TableSchema {
+	sort.Slice(columns, func(i, j int) bool {
+		if columns[i].Position != columns[j].Position {
+			return columns[i].Position < columns[j].Position
+		}
+		return columns[i].Name < columns[j].Name
+	})
+	s := &TableSchema{Name: name}
+	for _, c := range columns {
+		switch c.Kind {
+		case PartitionKeyColumn:
+			s.PartitionKeys = append(s.PartitionKeys, c.Name)
+		case ClusteringColumn:
+			s.ClusteringColumns = append(s.ClusteringColumns, c.Name)
+			direction := ASC
+			if c.Descending {
+				direction = DESC
+			}
+			s.ClusteringOrder = append(s.ClusteringOrder, ClusteringOrderColumn{Direction: direction, Column: c.Name})
+		}
+		s.Columns = append(s.Columns, c.ColumnSchema)
+	}
+	sort.Slice(s.Columns, func(i, j int) bool {
+		return s.Columns[i].Name < s.Columns[j].Name
+	})
+	return s
+}
+
+// structSchema returns the schema of the table gocassa would create for the given keys and fields
+func structSchema(name string, keys Keys, fields, types []string, order []ClusteringOrderColumn) *TableSchema {
+	columns := make([]columnInfo, len(fields))
+	for i, field := range fields {
+		c := columnInfo{ColumnSchema: ColumnSchema{Name: strings.ToLower(field), Type: normaliseType(types[i]), Kind: RegularColumn}, Position: -1}
+		for j, pk := range keys.PartitionKeys {
+			if pk == field {
+				c.Kind, c.Position = PartitionKeyColumn, j
+			}
+		}
+		for j, cc := range keys.ClusteringColumns {
+			if cc == field {
+				c.Kind, c.Position = ClusteringColumn, j
+			}
+		}
+		if containsString(keys.StaticColumns, field) {
+			c.Kind = StaticColumn
+		}
+		for _, o := range order {
+			if o.Column == field {
+				c.Descending = o.Direction == DESC
+			}
+		}
+		columns[i] = c
+	}
+	return newTableSchema(strings.ToLower(name), columns)
+}
+
+// schemaFlavour caches which schema tables the cluster of a keyspace has, once they were detected
+type schemaFlavour struct {
+	mtx      sync.Mutex
+	detected bool
+	legacy   bool
+}
+
+// legacySchema tells whether the cluster predates Cassandra 3.0, which moved the schema tables from the system
+// keyspace to system_schema. The release of the cluster is only queried once per keyspace.
+func (k *k) legacySchema() (bool, error) {
+	k.schema.mtx.Lock()
+	defer k.schema.mtx.Unlock()
+	if k.schema.detected {
+		return k.schema.legacy, nil
+	}
+	maps, err := k.queryExecutor().Query("SELECT release_version FROM system.local")
+	if err != nil {
+		return false, err
+	}
+	if len(maps) > 0 {
+		version, _ := maps[0]["release_version"].(string)
+		major, err := strconv.Atoi(strings.SplitN(version, ".", 2)[0])
+		k.schema.legacy = err == nil && major < 3
+	}
+	k.schema.detected = true
+	return k.schema.legacy, nil
+}
+
+// Tables returns table names in a keyspace
+func (k *k) Tables() ([]string, error) {
+	legacy, err := k.legacySchema()
+	if err != nil {
+		return nil, err
+	}
+	stmt, column := "SELECT table_name FROM system_schema.tables WHERE keyspace_name = ?", "table_name"
+	if legacy {
+		stmt, column = "SELECT columnfamily_name FROM system.schema_columnfamilies WHERE keyspace_name = ?", "columnfamily_name"
+	}
+	maps, err := k.queryExecutor().Query(stmt, k.name)
+	if err != nil {
+		return nil, err
+	}
+	ret := []string{}
+	for _, m := range maps {
+		ret = append(ret, m[column].(string))
+	}
+	return ret, nil
+}
+
+func (k *k) TableSchema(name string) (*TableSchema, error) {
+	legacy, err := k.legacySchema()
+	if err != nil {
+		return nil, err
+	}
+	name = strings.ToLower(name)
+	var columns []columnInfo
+	if legacy {
+		columns, err = k.legacyColumns(name)
+	} else {
+		columns, err = k.columns(name)
+	}
+	if err != nil || len(columns) == 0 {
+		return nil, err
+	}
+	return newTableSchema(name, columns), nil
+}
+
+func (k *k) columns(table string) ([]columnInfo, error) {
+	const stmt = "SELECT column_name, kind, position, clustering_order, type FROM system_schema.columns WHERE keyspace_name = ? AND table_name = ?"
+	maps, err := k.queryExecutor().Query(stmt, k.name, table)
+	if err != nil {
+		return nil, err
+	}
+	ret := []columnInfo{}
+	for _, m := range maps {
+		c := columnInfo{}
+		c.Name, _ = m["column_name"].(string)
+		c.Kind, _ = m["kind"].(string)
+		c.Position, _ = m["position"].(int)
+		order, _ := m["clustering_order"].(string)
+		c.Descending = strings.EqualFold(order, "desc")
+		typ, _ := m["type"].(string)
+		c.Type = normaliseType(typ)
+		ret = append(ret, c)
+	}
+	return ret, nil
+}
+
+func (k *k) legacyColumns(table string) ([]columnInfo, error) {
+	const stmt = "SELECT column_name, type, component_index, validator FROM system.schema_columns WHERE keyspace_name = ? AND columnfamily_name = ?"
+	maps, err := k.queryExecutor().Query(stmt, k.name, table)
+	if err != nil {
+		return nil, err
+	}
+	ret := []columnInfo{}
+	for _, m := range maps {
+		c := columnInfo{Position: -1}
+		c.Name, _ = m["column_name"].(string)
+		switch kind, _ := m["type"].(string); kind {
+		case "partition_key", "static", "regular":
+			c.Kind = kind
+		case "clustering_key":
+			c.Kind = ClusteringColumn
+		default: // compact_value
+			c.Kind = RegularColumn
+		}
+		if c.Kind == PartitionKeyColumn || c.Kind == ClusteringColumn {
+			// The index is null for the only partition key or clustering column
+			c.Position, _ = m["component_index"].(int)
+		}
+		validator, _ := m["validator"].(string)
+		c.Type, c.Descending = legacyType(validator)
+		ret = append(ret, c)
+	}
+	return ret, nil
+}
+
+var legacyTypes = map[string]string{
+	"AsciiType":         "ascii",
+	"BooleanType":       "boolean",
+	"ByteType":          "tinyint",
+	"BytesType":         "blob",
+	"CounterColumnType": "counter",
+	"DateType":          "timestamp",
+	"DecimalType":       "decimal",
+	"DoubleType":        "double",
+	"DurationType":      "duration",
+	"FloatType":         "float",
+	"InetAddressType":   "inet",
+	"Int32Type":         "int",
+	"IntegerType":       "varint",
+	"LongType":          "bigint",
+	"ShortType":         "smallint",
+	"SimpleDateType":    "date",
+	"TimeType":          "time",
+	"TimeUUIDType":      "timeuuid",
+	"TimestampType":     "timestamp",
+	"UTF8Type":          "text",
+	"UUIDType":          "uuid",
+}
+
+// legacyType converts the marshaller class names of the schema tables of Cassandra 2, eg.
+// "org.apache.cassandra.db.marshal.MapType(org.apache.cassandra.db.marshal.UTF8Type,...)", into CQL types. It also
+// tells whether the type is reversed, ie. the column is a clustering column in descending order.
+func legacyType(validator string) (string, bool) {
+	validator = strings.Replace(validator, "org.apache.cassandra.db.marshal.", "", -1)
+	name, args := validator, []string{}
+	if i := strings.Index(validator, "("); i >= 0 && strings.HasSuffix(validator, ")") {
+		name, args = validator[:i], splitTypeArgs(validator[i+1:len(validator)-1])
+	}
+	types := make([]string, len(args))
+	for i, arg := range args {
+		types[i], _ = legacyType(arg)
+	}
+
+	switch {
+	case name == "ReversedType" && len(types) == 1:
+		return types[0], true
+	case name == "FrozenType" && len(types) == 1:
+		return fmt.Sprintf("frozen<%s>", types[0]), false
+	case name == "ListType" && len(types) == 1:
+		return fmt.Sprintf("list<%s>", types[0]), false
+	case name == "SetType" && len(types) == 1:
+		return fmt.Sprintf("set<%s>", types[0]), false
+	case name == "MapType" && len(types) == 2:
+		return fmt.Sprintf("map<%s, %s>", types[0], types[1]), false
+	case name == "TupleType":
+		return fmt.Sprintf("tuple<%s>", strings.Join(types, ", ")), false
+	case name == "UserType" && len(args) >= 2:
+		// The arguments are the keyspace, the hex encoded name of the type and its fields
+		if udt, err := hex.DecodeString(args[1]); err == nil {
+			return string(udt), false
+		}
+	}
+	if typ, ok := legacyTypes[name]; ok {
+		return typ, false
+	}
+	return validator, false
+}
+
+// splitTypeArgs splits the comma separated arguments of a marshaller class, leaving nested arguments alone
+func splitTypeArgs(s string) []string {
+	args := []string{}
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				args = append(args, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(args, s[start:])
+}
+
+// normaliseType makes CQL types comparable, as Cassandra reports varchar columns as text
+func normaliseType(typ string) string {
+	typ = strings.ToLower(strings.Replace(typ, " ", "", -1))
+	typ = strings.Replace(typ, "varchar", "text", -1)
+	return strings.Replace(typ, ",", ", ", -1)
+}
+
+// alterTableStatements compares a live table with the one gocassa would create and returns the ALTER TABLE statements
+// adding the missing columns. Changes which can not be applied by adding columns, like a different type or primary
+// key, are returned as errors. Columns of the live table which the wanted one does not have are left alone.
+func alterTableStatements(keySpace string, live, wanted *TableSchema) ([]string, error) {
+	partitionKeys, livePartitionKeys := strings.Join(wanted.PartitionKeys, ", "), strings.Join(live.PartitionKeys, ", ")
+	if partitionKeys != livePartitionKeys {
+		return nil, fmt.Errorf("Partition key of table %s changed from (%s) to (%s)", live.Name, livePartitionKeys, partitionKeys)
+	}
+	clusteringColumns, liveClusteringColumns := strings.Join(wanted.ClusteringColumns, ", "), strings.Join(live.ClusteringColumns, ", ")
+	if clusteringColumns != liveClusteringColumns {
+		return nil, fmt.Errorf("Clustering columns of table %s changed from (%s) to (%s)", live.Name, liveClusteringColumns, clusteringColumns)
+	}
+	for i, o := range wanted.ClusteringOrder {
+		if liveOrder := live.ClusteringOrder[i]; o.Direction != liveOrder.Direction {
+			return nil, fmt.Errorf("Clustering order of column %s of table %s changed from %s to %s", o.Column, live.Name, liveOrder.Direction, o.Direction)
+		}
+	}
+
+	stmts := []string{}
+	for _, c := range wanted.Columns {
+		liveColumn, ok := live.Column(c.Name)
+		if !ok {
+			stmt := fmt.Sprintf("ALTER TABLE %s.%s ADD %s %s", keySpace, live.Name, c.Name, c.Type)
+			if c.Kind == StaticColumn {
+				stmt += " STATIC"
+			}
+			stmts = append(stmts, stmt)
+			continue
+		}
+		if liveColumn.Type != c.Type {
+			return nil, fmt.Errorf("Type of column %s of table %s changed from %s to %s", c.Name, live.Name, liveColumn.Type, c.Type)
+		}
+		if (liveColumn.Kind == StaticColumn) != (c.Kind == StaticColumn) {
+			return nil, fmt.Errorf("Column %s of table %s can not be made static or regular once created", c.Name, live.Name)
+		}
+	}
+	return stmts, nil
+}
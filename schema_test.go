@@ -0,0 +1,133 @@
+package gocassa
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Mock QueryExecutor that returns the rows of the first statement prefix the query matches
+type SchemaQE struct {
+	OptionCheckingQE
+	rows    map[string][]map[string]interface{}
+	queries map[string]int
+}
+
+func (qe SchemaQE) QueryContext(ctx context.Context, opts Options, stmt string, params ...interface{}) ([]map[string]interface{}, error) {
+	if qe.queries != nil {
+		qe.queries[stmt]++
+	}
+	for prefix, rows := range qe.rows {
+		if strings.HasPrefix(stmt, prefix) {
+			return rows, nil
+		}
+	}
+	return nil, nil
+}
+
+func (qe SchemaQE) Query(stmt string, params ...interface{}) ([]map[string]interface{}, error) {
+	return qe.QueryContext(context.Background(), Options{}, stmt, params...)
+}
+
+func schemaRow(name, kind string, position int, order, typ string) map[string]interface{} {
+	return map[string]interface{}{
+		"column_name":      name,
+		"kind":             kind,
+		"position":         position,
+		"clustering_order": order,
+		"type":             typ,
+	}
+}
+
+func legacySchemaRow(name, kind string, index interface{}, validator string) map[string]interface{} {
+	return map[string]interface{}{
+		"column_name":     name,
+		"type":            kind,
+		"component_index": index,
+		"validator":       validator,
+	}
+}
+
+func TestTableSchema(t *testing.T) {
+	expected := &TableSchema{
+		Name: "events",
+		Columns: []ColumnSchema{
+			{Name: "created", Type: "timestamp", Kind: ClusteringColumn},
+			{Name: "id", Type: "text", Kind: ClusteringColumn},
+			{Name: "owner", Type: "text", Kind: StaticColumn},
+			{Name: "tags", Type: "map<text, frozen<list<int>>>", Kind: RegularColumn},
+			{Name: "user_id", Type: "text", Kind: PartitionKeyColumn},
+		},
+		PartitionKeys:     []string{"user_id"},
+		ClusteringColumns: []string{"created", "id"},
+		ClusteringOrder:   []ClusteringOrderColumn{{Direction: DESC, Column: "created"}, {Direction: ASC, Column: "id"}},
+	}
+
+	for version, rows := range map[string]map[string][]map[string]interface{}{
+		"3.11.4": {
+			"SELECT table_name FROM system_schema.tables": {{"table_name": "events"}},
+			"SELECT column_name, kind, position, clustering_order, type FROM system_schema.columns": {
+				schemaRow("id", "clustering", 1, "asc", "text"),
+				schemaRow("user_id", "partition_key", 0, "none", "text"),
+				schemaRow("tags", "regular", -1, "none", "map<text, frozen<list<int>>>"),
+				schemaRow("created", "clustering", 0, "desc", "timestamp"),
+				schemaRow("owner", "static", -1, "none", "text"),
+			},
+		},
+		"2.1.9": {
+			"SELECT columnfamily_name FROM system.schema_columnfamilies": {{"columnfamily_name": "events"}},
+			"SELECT column_name, type, component_index, validator FROM system.schema_columns": {
+				legacySchemaRow("id", "clustering_key", 1, "org.apache.cassandra.db.marshal.UTF8Type"),
+				legacySchemaRow("user_id", "partition_key", nil, "org.apache.cassandra.db.marshal.UTF8Type"),
+				legacySchemaRow("tags", "regular", nil, "org.apache.cassandra.db.marshal.MapType(org.apache.cassandra.db.marshal.UTF8Type,org.apache.cassandra.db.marshal.FrozenType(org.apache.cassandra.db.marshal.ListType(org.apache.cassandra.db.marshal.Int32Type)))"),
+				legacySchemaRow("created", "clustering_key", 0, "org.apache.cassandra.db.marshal.ReversedType(org.apache.cassandra.db.marshal.TimestampType)"),
+				legacySchemaRow("owner", "static", nil, "org.apache.cassandra.db.marshal.UTF8Type"),
+			},
+		},
+	} {
+		rows["SELECT release_version FROM system.local"] = []map[string]interface{}{{"release_version": version}}
+		queries := map[string]int{}
+		ks := NewConnection(SchemaQE{OptionCheckingQE: OptionCheckingQE{opts: &Options{}}, rows: rows, queries: queries}).KeySpace("ks")
+
+		if tables, err := ks.Tables(); err != nil || !reflect.DeepEqual(tables, []string{"events"}) {
+			t.Fatal(version, tables, err)
+		}
+		if ok, err := ks.Exists("Events"); !ok || err != nil {
+			t.Fatal(version, ok, err)
+		}
+		schema, err := ks.TableSchema("Events")
+		if err != nil || !reflect.DeepEqual(schema, expected) {
+			t.Fatalf("%s: unexpected schema %+v (%v)", version, schema, err)
+		}
+		if c, ok := schema.Column("tags"); !ok || c.Type != "map<text, frozen<list<int>>>" {
+			t.Fatal(version, c)
+		}
+		// The release of the cluster is only queried once
+		if _, err := ks.WithQueryObserver(func(context.Context, QueryEvent) {}).TableSchema("events"); err != nil {
+			t.Fatal(version, err)
+		}
+		if n := queries["SELECT release_version FROM system.local"]; n != 1 {
+			t.Fatalf("%s: the release was queried %d times", version, n)
+		}
+	}
+
+	ks := NewConnection(SchemaQE{OptionCheckingQE: OptionCheckingQE{opts: &Options{}}}).KeySpace("ks")
+	if schema, err := ks.TableSchema("events"); schema != nil || err != nil {
+		t.Fatal(schema, err)
+	}
+}
+
+func TestLegacyType(t *testing.T) {
+	for validator, expected := range map[string]string{
+		"org.apache.cassandra.db.marshal.LongType":                                                                                      "bigint",
+		"org.apache.cassandra.db.marshal.SetType(org.apache.cassandra.db.marshal.TimeUUIDType)":                                         "set<timeuuid>",
+		"org.apache.cassandra.db.marshal.TupleType(org.apache.cassandra.db.marshal.Int32Type,org.apache.cassandra.db.marshal.UTF8Type)": "tuple<int, text>",
+		"org.apache.cassandra.db.marshal.UserType(ks,61646472657373,737472656574:org.apache.cassandra.db.marshal.UTF8Type)":             "address",
+		"org.apache.cassandra.db.marshal.SomeCustomType":                                                                                "SomeCustomType",
+	} {
+		if typ, desc := legacyType(validator); typ != expected || desc {
+			t.Errorf("Expected %s for %s but got %s", expected, validator, typ)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package gocassa
+
+import (
+	"context"
+	"fmt"
+)
+
+// sortedSetScore is a row of the table holding the members of the sets ordered by score, highest first
+type sortedSetScore struct {
+	SetName string
+	Score   float64
+	Member  string
+}
+
+// sortedSetMember is a row of the table holding the score of each member of the sets
+type sortedSetMember struct {
+	SetName string
+	Member  string
+	Score   float64
+}
+
+type sortedSetT struct {
+	tableGroup
+	scores  Table
+	members Table
+}
+
+func newSortedSetT(name string, scores, members Table) *sortedSetT {
+	return &sortedSetT{
+		tableGroup: tableGroup{name: name, tables: []TableChanger{scores.(TableChanger), members.(TableChanger)}},
+		scores:     scores,
+		members:    members,
+	}
+}
+
+// score reads the score of the member, it returns false if it is not a member of the set
+func (s *sortedSetT) score(ctx context.Context, opts Options, set, member string) (float64, bool, error) {
+	row := sortedSetMember{}
+	err := s.members.Where(Eq("SetName", set), Eq("Member", member)).ReadOne(&row).WithOptions(opts).RunContext(ctx)
+	if _, ok := err.(RowNotFoundError); ok {
+		return 0, false, nil
+	}
+	return row.Score, err == nil, err
+}
+
+func (s *sortedSetT) Add(set, member string, score float64) Op {
+	return newDeferredOp(true, func(ctx context.Context, opts Options) (Op, error) {
+		old, ok, err := s.score(ctx, opts, set, member)
+		if err != nil {
+			return nil, err
+		}
+		op := Noop()
+		if ok && old != score {
+			op = op.Add(s.scores.Where(Eq("SetName", set), Eq("Score", old), Eq("Member", member)).Delete())
+		}
+		return op.Add(
+			s.scores.Set(sortedSetScore{SetName: set, Score: score, Member: member}),
+			s.members.Set(sortedSetMember{SetName: set, Member: member, Score: score}),
+		), nil
+	})
+}
+
+func (s *sortedSetT) Score(set, member string, score *float64) Op {
+	return newDeferredOp(false, func(ctx context.Context, opts Options) (Op, error) {
+		current, ok, err := s.score(ctx, opts, set, member)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, RowNotFoundError{}
+		}
+		*score = current
+		return Noop(), nil
+	})
+}
+
+func (s *sortedSetT) Rank(set, member string, rank *int) Op {
+	return newDeferredOp(false, func(ctx context.Context, opts Options) (Op, error) {
+		score, ok, err := s.score(ctx, opts, set, member)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, RowNotFoundError{}
+		}
+		// The members ranked before it are counted as they are streamed, reading their names only
+		iter := s.scores.WithOptions(opts).WithOptions(Options{Select: []string{"Member"}}).
+			Where(Eq("SetName", set), GTE("Score", score)).
+			IterContext(ctx)
+		row := sortedSetScore{}
+		for i := 0; iter.Next(&row); i++ {
+			if row.Member == member {
+				*rank = i
+				return Noop(), iter.Close()
+			}
+		}
+		if err := iter.Close(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("Member %s of set %s is missing from the scores table", member, set)
+	})
+}
+
+func (s *sortedSetT) Top(set string, n int, members *[]SortedSetMember) Op {
+	return s.scores.Where(Eq("SetName", set)).Read(members).WithOptions(Options{Limit: n})
+}
+
+func (s *sortedSetT) RangeByScore(set string, min, max float64, members *[]SortedSetMember) Op {
+	return s.scores.Where(Eq("SetName", set), GTE("Score", min), LTE("Score", max)).Read(members)
+}
+
+func (s *sortedSetT) Remove(set, member string) Op {
+	return newDeferredOp(true, func(ctx context.Context, opts Options) (Op, error) {
+		score, ok, err := s.score(ctx, opts, set, member)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return Noop(), nil
+		}
+		return s.scores.Where(Eq("SetName", set), Eq("Score", score), Eq("Member", member)).Delete().Add(
+			s.members.Where(Eq("SetName", set), Eq("Member", member)).Delete(),
+		), nil
+	})
+}
+
+func (s *sortedSetT) WithOptions(o Options) SortedSetTable {
+	return newSortedSetT(s.name, s.scores.WithOptions(o), s.members.WithOptions(o))
+}
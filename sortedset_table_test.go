@@ -0,0 +1,140 @@
+package gocassa
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSortedSetTable(t *testing.T) {
+	tbl := ns.SortedSetTable("leaderboard")
+	createIf(tbl, t)
+	for member, score := range map[string]float64{"jane": 30, "john": 10, "joe": 20, "jack": 20} {
+		if err := tbl.Add("game", member, score).Run(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Changing the score of a member moves it
+	if err := tbl.Add("game", "john", 40).Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	top := []SortedSetMember{}
+	if err := tbl.Top("game", 3, &top).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(top, []SortedSetMember{{"john", 40}, {"jane", 30}, {"jack", 20}}) {
+		t.Fatal(top)
+	}
+	var rank int
+	if err := tbl.Rank("game", "joe", &rank).Run(); err != nil || rank != 3 {
+		t.Fatal(rank, err)
+	}
+	var score float64
+	if err := tbl.Score("game", "jane", &score).Run(); err != nil || score != 30 {
+		t.Fatal(score, err)
+	}
+	members := []SortedSetMember{}
+	if err := tbl.RangeByScore("game", 15, 30, &members).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(members, []SortedSetMember{{"jane", 30}, {"jack", 20}, {"joe", 20}}) {
+		t.Fatal(members)
+	}
+
+	if err := tbl.Remove("game", "jane").Run(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tbl.Remove("game", "jane").Run(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tbl.Rank("game", "jane", &rank).Run().(RowNotFoundError); !ok {
+		t.Fatal("Expected jane to be removed")
+	}
+	if err := tbl.Top("game", 10, &top).Run(); err != nil || len(top) != 3 {
+		t.Fatal(top, err)
+	}
+}
+
+// Mock QueryExecutor that reads the score of a member and records the batches it executes
+type SortedSetQE struct {
+	OptionCheckingQE
+	batches *[][]string
+}
+
+func (qe SortedSetQE) QueryContext(ctx context.Context, opts Options, stmt string, params ...interface{}) ([]map[string]interface{}, error) {
+	return []map[string]interface{}{{"setname": "game", "member": "jane", "score": 3.0}}, nil
+}
+
+func (qe SortedSetQE) ExecuteBatch(ctx context.Context, batchType BatchType, stmts []string, params [][]interface{}) error {
+	if batchType != LoggedBatch {
+		return ctx.Err()
+	}
+	*qe.batches = append(*qe.batches, stmts)
+	return nil
+}
+
+func TestSortedSetTableBatches(t *testing.T) {
+	batches := [][]string{}
+	qe := SortedSetQE{OptionCheckingQE: OptionCheckingQE{opts: &Options{}}, batches: &batches}
+	tbl := NewConnection(qe).KeySpace("ks").SortedSetTable("leaderboard")
+
+	stmt, err := tbl.CreateStatement()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The partition key must not be named after a reserved keyword such as SET, as identifiers are not quoted
+	expected := strings.Join([]string{
+		"CREATE TABLE ks.leaderboard_sortedSet_scores (",
+		"    member varchar,",
+		"    score double,",
+		"    setname varchar,",
+		"    PRIMARY KEY ((setname), score, member)",
+		")",
+		"WITH CLUSTERING ORDER BY (Score DESC, Member ASC)",
+		";",
+		"CREATE TABLE ks.leaderboard_sortedSet_members (",
+		"    member varchar,",
+		"    score double,",
+		"    setname varchar,",
+		"    PRIMARY KEY ((setname), member)",
+		")",
+		";",
+	}, "\n")
+	if stmt != expected {
+		t.Fatalf("Expected:\n%s\nGot:\n%s", expected, stmt)
+	}
+
+	if err := tbl.Add("game", "jane", 5).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tbl.Remove("game", "jane").Run(); err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) != 2 || len(batches[0]) != 3 || len(batches[1]) != 2 {
+		t.Fatal(batches)
+	}
+	if !strings.HasPrefix(batches[0][0], "DELETE FROM ks.leaderboard_sortedSet_scores") ||
+		!strings.HasPrefix(batches[1][1], "DELETE FROM ks.leaderboard_sortedSet_members") {
+		t.Fatal(batches)
+	}
+
+	// Combined with other ops, the writes of Add and Remove are part of the same batch
+	batches = batches[:0]
+	err = tbl.Add("game", "jane", 5).Add(tbl.Remove("game", "jane")).RunAtomically()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) != 1 || len(batches[0]) != 5 {
+		t.Fatal(batches)
+	}
+	// Reads can not be batched, rather than being run one after the other
+	var score float64
+	if err := tbl.Add("game", "jane", 5).Add(tbl.Score("game", "jane", &score)).RunAtomically(); err == nil {
+		t.Fatal("Expected batching a read to fail")
+	}
+	if len(batches) != 1 {
+		t.Fatal(batches)
+	}
+}
@@ -0,0 +1,121 @@
+package gocassa
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+)
+
+// maxCachedStatements bounds the number of statements cached per table, shapes beyond it are built every time
+const maxCachedStatements = 1024
+
+// statementCache holds the CQL generated for each shape of op on a table. Ops of the same shape reuse the same
+// statement text, which is not only cheaper than building it again but lets gocql prepare the statement once.
+type statementCache struct {
+	mtx   sync.RWMutex
+	stmts map[string]string
+	max   int
+}
+
+func newStatementCache() *statementCache {
+	return &statementCache{
+		stmts: map[string]string{},
+		max:   maxCachedStatements,
+	}
+}
+
+// get returns the statement cached for the shape, building and caching it if there is none
+func (c *statementCache) get(shape []byte, build func() string) string {
+	c.mtx.RLock()
+	// The conversion does not allocate when used as a map key
+	stmt, ok := c.stmts[string(shape)]
+	c.mtx.RUnlock()
+	if ok {
+		return stmt
+	}
+
+	stmt = build()
+	c.mtx.Lock()
+	if len(c.stmts) < c.max {
+		c.stmts[string(shape)] = stmt
+	}
+	c.mtx.Unlock()
+	return stmt
+}
+
+// statementShape identifies a statement by everything which goes into its text, but none of its values
+type statementShape struct {
+	buf bytes.Buffer
+}
+
+func newStatementShape(opType uint8, keySpaceName, tableName string) *statementShape {
+	s := &statementShape{}
+	s.buf.WriteByte(opType)
+	s.add(keySpaceName, tableName)
+	return s
+}
+
+func (s *statementShape) add(parts ...string) {
+	for _, p := range parts {
+		s.buf.WriteString(p)
+		s.buf.WriteByte(0)
+	}
+}
+
+func (s *statementShape) flag(f bool) {
+	if f {
+		s.buf.WriteByte(1)
+	} else {
+		s.buf.WriteByte(0)
+	}
+}
+
+func (s *statementShape) relations(rs []Relation) {
+	s.buf.WriteByte(byte(len(rs)))
+	for _, r := range rs {
+		s.buf.WriteByte(byte(r.op))
+		s.add(r.key)
+	}
+}
+
+func (s *statementShape) using(opts Options, ttl bool) {
+	s.flag(ttl && opts.TTL != 0)
+	s.flag(!opts.Timestamp.IsZero())
+}
+
+func (s *statementShape) cas(c *casCondition) {
+	if c == nil {
+		s.flag(false)
+		return
+	}
+	s.flag(true)
+	s.flag(c.notExists)
+	s.relations(c.conditions)
+}
+
+func (s *statementShape) Bytes() []byte {
+	return s.buf.Bytes()
+}
+
+// sortedKeys returns the keys of the map in order, so the same fields always produce the same statement
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// relationValues returns the values bound by the relations, see Relation.cql
+func relationValues(rs []Relation) []interface{} {
+	vals := []interface{}{}
+	for _, r := range rs {
+		if r.op == in {
+			vals = append(vals, r.terms)
+			continue
+		}
+		vals = append(vals, r.terms...)
+	}
+	return vals
+}
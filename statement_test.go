@@ -0,0 +1,115 @@
+package gocassa
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type Account struct {
+	Id      string
+	Name    string
+	Email   string
+	Balance int
+	Tags    map[string]string
+	Created time.Time
+}
+
+func accountTable() Table {
+	conn := &connection{q: OptionCheckingQE{opts: &Options{}}}
+	return conn.KeySpace("ks").Table("account", Account{}, Keys{PartitionKeys: []string{"Id"}}).WithOptions(Options{
+		TableName: "account",
+	})
+}
+
+func TestDeterministicStatements(t *testing.T) {
+	tbl := accountTable()
+	a := Account{Id: "1", Name: "Joe", Email: "joe@example.com", Balance: 10, Created: time.Unix(0, 0)}
+
+	stmt, vals := tbl.Set(a).GenerateStatement()
+	if stmt != "UPDATE ks.account SET balance = ?, created = ?, email = ?, name = ?, tags = ? WHERE id = ?" ||
+		!reflect.DeepEqual(vals, []interface{}{10, a.Created, a.Email, a.Name, a.Tags, a.Id}) {
+		t.Fatal(stmt, vals)
+	}
+	for i := 0; i < 20; i++ {
+		if s, _ := tbl.Set(a).GenerateStatement(); s != stmt {
+			t.Fatal(s)
+		}
+	}
+
+	stmt, vals = tbl.Where(Eq("Id", "1")).Update(map[string]interface{}{
+		"Tags": MapSetFields(map[string]interface{}{"b": "2", "a": "1", "c": "3"}),
+		"Name": "Jim",
+	}).GenerateStatement()
+	if stmt != "UPDATE ks.account SET Name = ?, Tags[?] = ?, Tags[?] = ?, Tags[?] = ? WHERE id = ?" ||
+		!reflect.DeepEqual(vals, []interface{}{"Jim", "a", "1", "b", "2", "c", "3", "1"}) {
+		t.Fatal(stmt, vals)
+	}
+
+	stmt, _ = tbl.Where(Eq("Id", "1")).Read(&[]Account{}).GenerateStatement()
+	if stmt != "SELECT balance, created, email, id, name, tags FROM ks.account WHERE id = ?" {
+		t.Fatal(stmt)
+	}
+}
+
+func TestStatementCache(t *testing.T) {
+	tbl := accountTable()
+
+	// Ops of the same shape share the statement, whatever their values
+	stmt1, vals1 := tbl.Where(Eq("Id", "1")).Read(&[]Account{}).WithOptions(Options{Limit: 1}).GenerateStatement()
+	stmt2, vals2 := tbl.Where(Eq("Id", "2")).Read(&[]Account{}).WithOptions(Options{Limit: 2}).GenerateStatement()
+	if stmt1 != stmt2 || !reflect.DeepEqual(vals1, []interface{}{"1", 1}) || !reflect.DeepEqual(vals2, []interface{}{"2", 2}) {
+		t.Fatal(stmt1, vals1, stmt2, vals2)
+	}
+	ts := time.Now()
+	del1, _ := tbl.Where(Eq("Id", "1")).Delete().WithOptions(Options{Timestamp: ts}).GenerateStatement()
+	del2, _ := tbl.Where(Eq("Id", "2")).Delete().WithOptions(Options{Timestamp: ts.Add(time.Second)}).GenerateStatement()
+	if del1 != del2 {
+		t.Fatal(del1, del2)
+	}
+
+	// ... but not with ops of other shapes
+	for _, op := range []Op{
+		tbl.Where(Eq("Id", "1")).Read(&[]Account{}),
+		tbl.Where(GT("Id", "1")).Read(&[]Account{}).WithOptions(Options{Limit: 1}),
+		tbl.Where(Eq("Id", "1")).Read(&[]Account{}).WithOptions(Options{Limit: 1, Select: []string{"name"}}),
+		tbl.WithOptions(Options{TableName: "account2"}).Where(Eq("Id", "1")).Read(&[]Account{}).WithOptions(Options{Limit: 1}),
+		tbl.Where(Eq("Id", "1")).Delete(),
+		tbl.Where(Eq("Id", "1")).DeleteIf(nil, nil),
+	} {
+		if stmt, _ := op.GenerateStatement(); stmt == stmt1 || stmt == del1 {
+			t.Fatal(stmt)
+		}
+	}
+
+	if stmts := cachedStatements(tbl); len(stmts) != 8 {
+		t.Fatalf("Expected 8 cached statements but got %v", stmts)
+	}
+}
+
+func cachedStatements(tbl Table) map[string]string {
+	return tbl.(t).info.statements.stmts
+}
+
+func BenchmarkGenerateStatement(b *testing.B) {
+	tbl := accountTable()
+	a := Account{Id: "1", Name: "Joe", Email: "joe@example.com", Balance: 10, Created: time.Unix(0, 0)}
+	op := tbl.WithOptions(Options{TTL: time.Hour}).Set(a).(*singleOp)
+
+	b.Run("Cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			op.generateWrite(op.options)
+		}
+	})
+	b.Run("Uncached", func(b *testing.B) {
+		cache := op.f.t.info.statements
+		defer func() { op.f.t.info.statements = cache }()
+		op.f.t.info.statements = &statementCache{stmts: map[string]string{}}
+
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			op.generateWrite(op.options)
+		}
+	})
+}
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"fmt"
 	"reflect"
-	"strconv"
 	"strings"
 
 	r "github.com/gocassa/gocassa/reflect"
@@ -25,6 +24,9 @@ type tableInfo struct {
 	fieldNames     map[string]struct{} // This is here only to check containment
 	fields         []string
 	fieldValues    []interface{}
+	metadata       []string // WRITETIME() and TTL() selectors the entity has fields for
+	fieldOptions   map[string][]string // Tag options of the fields, by lower cased field name
+	statements     *statementCache
 }
 
 func newTableInfo(keyspace, name string, keys Keys, entity interface{}, fieldSource map[string]interface{}) *tableInfo {
@@ -34,34 +36,55 @@ func newTableInfo(keyspace, name string, keys Keys, entity interface{}, fieldSou
 		marshalSource: entity,
 		keys:          keys,
 		fieldSource:   fieldSource,
+		statements:    newStatementCache(),
 	}
-	fields := []string{}
-	values := []interface{}{}
-	for k, v := range fieldSource {
-		fields = append(fields, k)
-		values = append(values, v)
-	}
+	fields, values := keyValues(fieldSource)
 	cinf.fieldNames = map[string]struct{}{}
 	for _, v := range fields {
 		cinf.fieldNames[v] = struct{}{}
 	}
 	cinf.fields = fields
 	cinf.fieldValues = values
+	if entity != nil {
+		cinf.metadata = r.MetadataFields(entity)
+		cinf.fieldOptions = fieldOptions(entity)
+	}
 	return cinf
 }
 
+// fieldTypes returns the CQL types of the fields
+func (ti *tableInfo) fieldTypes() ([]string, error) {
+	types := make([]string, len(ti.fields))
+	for i, field := range ti.fields {
+		typ, err := columnTypeOf(ti.fieldValues[i], ti.fieldOptions[strings.ToLower(field)])
+		if err != nil {
+			return nil, err
+		}
+		types[i] = typ
+	}
+	return types, nil
+}
+
+// columnValue returns the value stored in the column of the field, see columnValue
+func (ti *tableInfo) columnValue(field string, value interface{}) interface{} {
+	if len(ti.fieldOptions) == 0 {
+		return value
+	}
+	return columnValue(value, ti.fieldOptions[strings.ToLower(field)])
+}
+
 func (t *t) zero() interface{} {
 	return reflect.New(reflect.TypeOf(t.info.marshalSource)).Interface()
 }
 
 // Since we cant have Map -> [(k, v)] we settle for Map -> ([k], [v])
 // #tuplelessLifeSucks
+// The keys are sorted so the same map always results in the same statement.
 func keyValues(m map[string]interface{}) ([]string, []interface{}) {
-	keys := []string{}
-	values := []interface{}{}
-	for k, v := range m {
-		keys = append(keys, k)
-		values = append(values, v)
+	keys := sortedKeys(m)
+	values := make([]interface{}, len(keys))
+	for i, k := range keys {
+		values[i] = m[k]
 	}
 	return keys, values
 }
@@ -84,6 +107,8 @@ func (t t) Where(rs ...Relation) Filter {
 	}
 }
 
+// generateFieldNames returns the selected fields, or all the fields of the table followed by the WRITETIME() and TTL()
+// of the columns the entity has metadata fields for. Metadata can be selected explicitly, eg. "WRITETIME(name)".
 func (t t) generateFieldNames(sel []string) string {
 	xs := make([]string, len(t.info.fields))
 	if len(sel) > 0 {
@@ -92,6 +117,9 @@ func (t t) generateFieldNames(sel []string) string {
 		for i, v := range t.info.fields {
 			xs[i] = strings.ToLower(v)
 		}
+		for _, v := range t.info.metadata {
+			xs = append(xs, strings.ToLower(v))
+		}
 	}
 	return strings.Join(xs, ", ")
 }
@@ -120,6 +148,15 @@ func removeFields(m map[string]interface{}, s []string) map[string]interface{} {
 	return ret
 }
 
+func containsString(s []string, v string) bool {
+	for _, sv := range s {
+		if sv == v {
+			return true
+		}
+	}
+	return false
+}
+
 func transformFields(m map[string]interface{}) {
 	for k, v := range m {
 		switch t := v.(type) {
@@ -133,7 +170,7 @@ func transformFields(m map[string]interface{}) {
 //   VALUES ('cfd66ccc-d857-4e90-b1e5-df98a3d40cd6', 'johndoe')
 //
 // Gotcha: primkey must be first
-func insertStatement(keySpaceName, cfName string, fieldNames []string, opts Options) string {
+func insertStatement(keySpaceName, cfName string, fieldNames []string, ifNotExists bool, opts Options) string {
 	placeHolders := make([]string, len(fieldNames))
 	for i := 0; i < len(fieldNames); i++ {
 		placeHolders[i] = "?"
@@ -150,10 +187,14 @@ func insertStatement(keySpaceName, cfName string, fieldNames []string, opts Opti
 		strings.Join(lowerFieldNames, ", "),
 		strings.Join(placeHolders, ", ")))
 
+	if ifNotExists {
+		buf.WriteString(" IF NOT EXISTS")
+	}
+
 	// Apply options
-	if opts.TTL != 0 {
-		buf.WriteString(" USING TTL ")
-		buf.WriteString(strconv.FormatFloat(opts.TTL.Seconds(), 'f', 0, 64))
+	if using := usingStatement(opts, true); using != "" {
+		buf.WriteString(" ")
+		buf.WriteString(using)
 	}
 
 	return buf.String()
@@ -167,34 +208,64 @@ func (t t) Set(i interface{}) Op {
 	ks := append(t.info.keys.PartitionKeys, t.info.keys.ClusteringColumns...)
 	updFields := removeFields(m, ks)
 	if len(updFields) == 0 {
-		return newWriteOp(t.keySpace.qe, filter{
+		return newWriteOp(t.keySpace.queryExecutor(), filter{
 			t: t,
 		}, insertOpType, m)
 	}
 	transformFields(updFields)
 	rels := relations(t.info.keys, m)
-	return newWriteOp(t.keySpace.qe, filter{
+	return newWriteOp(t.keySpace.queryExecutor(), filter{
 		t:  t,
 		rs: rels,
 	}, updateOpType, updFields)
 }
 
+func (t t) SetIfNotExists(i interface{}, result *CASResult) Op {
+	m, ok := toMap(i)
+	if !ok {
+		panic("SetIfNotExists: Incompatible type")
+	}
+	op := newWriteOp(t.keySpace.queryExecutor(), filter{
+		t: t,
+	}, insertOpType, m)
+	op.cas = &casCondition{notExists: true, result: result}
+	return op
+}
+
 func (t t) Create() error {
 	if stmt, err := t.CreateStatement(); err != nil {
 		return err
+	} else if err := t.createTypes(); err != nil {
+		return err
 	} else {
-		return t.keySpace.qe.Execute(stmt)
+		return t.keySpace.queryExecutor().Execute(stmt)
 	}
 }
 
 func (t t) CreateIfNotExist() error {
 	if stmt, err := t.CreateIfNotExistStatement(); err != nil {
 		return err
+	} else if err := t.createTypes(); err != nil {
+		return err
 	} else {
-		return t.keySpace.qe.Execute(stmt)
+		return t.keySpace.queryExecutor().Execute(stmt)
 	}
 }
 
+// createTypes creates the user defined types the fields of the table are stored as
+func (t t) createTypes() error {
+	stmts, err := createTypeStmts(t.keySpace.name, t.info.fieldValues)
+	if err != nil {
+		return err
+	}
+	for _, stmt := range stmts {
+		if err := t.keySpace.queryExecutor().Execute(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (t t) Recreate() error {
 	if ex, err := t.keySpace.Exists(t.Name()); ex && err == nil {
 		if err := t.keySpace.DropTable(t.Name()); err != nil {
@@ -206,31 +277,96 @@ func (t t) Recreate() error {
 	return t.Create()
 }
 
+func (t t) SyncStatements() ([]string, error) {
+	live, err := t.keySpace.TableSchema(t.Name())
+	if err != nil {
+		return nil, err
+	}
+	var stmts []string
+	if live == nil {
+		stmt, err := t.CreateIfNotExistStatement()
+		if err != nil {
+			return nil, err
+		}
+		stmts = []string{stmt}
+	} else {
+		types, err := t.info.fieldTypes()
+		if err != nil {
+			return nil, err
+		}
+		wanted := structSchema(t.Name(), t.info.keys, t.info.fields, types, t.options.ClusteringOrder)
+		if stmts, err = alterTableStatements(t.keySpace.name, live, wanted); err != nil || len(stmts) == 0 {
+			return stmts, err
+		}
+	}
+	// The user defined types of the columns are created beforehand, in case new columns need them
+	typeStmts, err := createTypeStmts(t.keySpace.name, t.info.fieldValues)
+	if err != nil {
+		return nil, err
+	}
+	return append(typeStmts, stmts...), nil
+}
+
+func (t t) Sync() error {
+	stmts, err := t.SyncStatements()
+	if err != nil {
+		return err
+	}
+	for _, stmt := range stmts {
+		if err := t.keySpace.queryExecutor().Execute(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t t) AlterStatement() (string, error) {
+	return alterTablePropertiesStmt(t.keySpace.name, t.Name(), t.options.tableProperties())
+}
+
+func (t t) Alter() error {
+	stmt, err := t.AlterStatement()
+	if err != nil {
+		return err
+	}
+	return t.keySpace.queryExecutor().Execute(stmt)
+}
+
 func (t t) CreateStatement() (string, error) {
+	types, err := t.info.fieldTypes()
+	if err != nil {
+		return "", err
+	}
 	return createTable(t.keySpace.name,
 		t.Name(),
 		t.info.keys.PartitionKeys,
 		t.info.keys.ClusteringColumns,
+		t.info.keys.StaticColumns,
 		t.info.fields,
-		t.info.fieldValues,
+		types,
 		t.options.ClusteringOrder,
 		t.info.keys.Compound,
 		t.options.CompactStorage,
-		t.options.Compressor,
+		t.options.tableProperties(),
 	)
 }
 
 func (t t) CreateIfNotExistStatement() (string, error) {
+	types, err := t.info.fieldTypes()
+	if err != nil {
+		return "", err
+	}
 	return createTableIfNotExist(t.keySpace.name,
 		t.Name(),
 		t.info.keys.PartitionKeys,
 		t.info.keys.ClusteringColumns,
+		t.info.keys.StaticColumns,
 		t.info.fields,
-		t.info.fieldValues,
+		types,
 		t.options.ClusteringOrder,
 		t.info.keys.Compound,
 		t.options.CompactStorage,
-		t.options.Compressor,
+		t.options.tableProperties(),
 	)
 }
 
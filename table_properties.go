@@ -0,0 +1,179 @@
+package gocassa
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TableProperties are the properties of a table, set when it is created (CREATE TABLE ... WITH) and changed with
+// TableChanger.Alter. Properties left empty are omitted, so the defaults of Cassandra apply. For example, a time
+// series table keeping a week of data:
+//
+//	Options{Properties: &TableProperties{
+//		Compaction: TimeWindowCompaction("DAYS", 1),
+//		DefaultTTL: 7 * 24 * time.Hour,
+//		GCGrace:    GCGrace(time.Hour),
+//	}}
+type TableProperties struct {
+	// Compaction is the compaction strategy of the table
+	Compaction *Compaction
+	// Compression holds the compression options, eg. {"class": "LZ4Compressor", "chunk_length_in_kb": "64"}
+	Compression map[string]string
+	// DefaultTTL is the TTL of the rows written without one. It is truncated to second precision.
+	DefaultTTL time.Duration
+	// GCGrace is how long tombstones are kept before being purged. It is truncated to second precision. If nil, it
+	// is omitted.
+	GCGrace *time.Duration
+	// Caching holds the caching options, eg. {"keys": "ALL", "rows_per_partition": "NONE"}
+	Caching map[string]string
+	// BloomFilterFPChance is the false positive probability of the bloom filters of the SSTables
+	BloomFilterFPChance float64
+	// SpeculativeRetry is the speculative retry policy, eg. "99PERCENTILE" or "10ms"
+	SpeculativeRetry string
+	// Comment describes the table
+	Comment string
+}
+
+// Compaction is a compaction strategy, ie. the class implementing it and its options
+type Compaction struct {
+	Class   string
+	Options map[string]string
+}
+
+// SizeTieredCompaction returns the default compaction strategy of Cassandra
+func SizeTieredCompaction() *Compaction {
+	return &Compaction{Class: "SizeTieredCompactionStrategy"}
+}
+
+// LeveledCompaction returns the leveled compaction strategy, suited to read heavy tables
+func LeveledCompaction() *Compaction {
+	return &Compaction{Class: "LeveledCompactionStrategy"}
+}
+
+// TimeWindowCompaction returns the time window compaction strategy, suited to time series written with a TTL. The
+// unit is one of "MINUTES", "HOURS" or "DAYS".
+func TimeWindowCompaction(unit string, size int) *Compaction {
+	return &Compaction{
+		Class: "TimeWindowCompactionStrategy",
+		Options: map[string]string{
+			"compaction_window_unit": unit,
+			"compaction_window_size": strconv.Itoa(size),
+		},
+	}
+}
+
+// GCGrace returns a pointer to the duration, to be used as TableProperties.GCGrace
+func GCGrace(d time.Duration) *time.Duration {
+	return &d
+}
+
+// Merge returns new TableProperties which are a right biased merge of the two initial ones
+func (p TableProperties) Merge(neu TableProperties) TableProperties {
+	ret := p
+	if neu.Compaction != nil {
+		ret.Compaction = neu.Compaction
+	}
+	if neu.Compression != nil {
+		ret.Compression = neu.Compression
+	}
+	if neu.DefaultTTL != 0 {
+		ret.DefaultTTL = neu.DefaultTTL
+	}
+	if neu.GCGrace != nil {
+		ret.GCGrace = neu.GCGrace
+	}
+	if neu.Caching != nil {
+		ret.Caching = neu.Caching
+	}
+	if neu.BloomFilterFPChance != 0 {
+		ret.BloomFilterFPChance = neu.BloomFilterFPChance
+	}
+	if len(neu.SpeculativeRetry) > 0 {
+		ret.SpeculativeRetry = neu.SpeculativeRetry
+	}
+	if len(neu.Comment) > 0 {
+		ret.Comment = neu.Comment
+	}
+	return ret
+}
+
+// cql returns the properties as "name = value" strings, sorted by name
+func (p TableProperties) cql() []string {
+	props := []string{}
+	if p.BloomFilterFPChance != 0 {
+		props = append(props, fmt.Sprintf("bloom_filter_fp_chance = %v", p.BloomFilterFPChance))
+	}
+	if len(p.Caching) > 0 {
+		props = append(props, fmt.Sprintf("caching = %s", cqlMap(p.Caching)))
+	}
+	if len(p.Comment) > 0 {
+		props = append(props, fmt.Sprintf("comment = %s", cqlString(p.Comment)))
+	}
+	if p.Compaction != nil {
+		compaction := map[string]string{"class": p.Compaction.Class}
+		for k, v := range p.Compaction.Options {
+			compaction[k] = v
+		}
+		props = append(props, fmt.Sprintf("compaction = %s", cqlMap(compaction)))
+	}
+	if len(p.Compression) > 0 {
+		props = append(props, fmt.Sprintf("compression = %s", cqlMap(p.Compression)))
+	}
+	if p.DefaultTTL != 0 {
+		props = append(props, fmt.Sprintf("default_time_to_live = %d", int(p.DefaultTTL.Seconds())))
+	}
+	if p.GCGrace != nil {
+		props = append(props, fmt.Sprintf("gc_grace_seconds = %d", int(p.GCGrace.Seconds())))
+	}
+	if len(p.SpeculativeRetry) > 0 {
+		props = append(props, fmt.Sprintf("speculative_retry = %s", cqlString(p.SpeculativeRetry)))
+	}
+	return props
+}
+
+// tableProperties returns the properties of tables created with the options. Compressor is kept for backwards
+// compatibility and sets the compression class, unless Properties sets one.
+func (o Options) tableProperties() TableProperties {
+	props := TableProperties{}
+	if o.Properties != nil {
+		props = *o.Properties
+	}
+	if len(o.Compressor) > 0 {
+		if _, ok := props.Compression["class"]; !ok {
+			compression := map[string]string{"class": o.Compressor}
+			for k, v := range props.Compression {
+				compression[k] = v
+			}
+			props.Compression = compression
+		}
+	}
+	return props
+}
+
+func alterTablePropertiesStmt(keySpace, cf string, props TableProperties) (string, error) {
+	cql := props.cql()
+	if len(cql) == 0 {
+		return "", fmt.Errorf("No properties to alter table %s with", cf)
+	}
+	return fmt.Sprintf("ALTER TABLE %s.%s WITH %s", keySpace, cf, strings.Join(cql, " AND ")), nil
+}
+
+func cqlString(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+func cqlMap(m map[string]string) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	entries := make([]string, len(keys))
+	for i, k := range keys {
+		entries[i] = fmt.Sprintf("%s: %s", cqlString(k), cqlString(m[k]))
+	}
+	return "{" + strings.Join(entries, ", ") + "}"
+}
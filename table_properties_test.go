@@ -0,0 +1,69 @@
+package gocassa
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTablePropertiesStatements(t *testing.T) {
+	stmts := []string{}
+	qe := StatementRecordingQE{OptionCheckingQE: OptionCheckingQE{opts: &Options{}}, stmts: &stmts}
+	tbl := NewConnection(qe).KeySpace("ks").Table("events", Event{}, Keys{
+		PartitionKeys:     []string{"UserId"},
+		ClusteringColumns: []string{"Created", "Id"},
+	}).WithOptions(Options{
+		TableName:       "events",
+		ClusteringOrder: []ClusteringOrderColumn{{Column: "created", Direction: DESC}},
+		Properties: &TableProperties{
+			Compaction: TimeWindowCompaction("DAYS", 1),
+			DefaultTTL: 7 * 24 * time.Hour,
+			GCGrace:    GCGrace(0),
+			Comment:    "User's events",
+		},
+	})
+
+	stmt, err := tbl.CreateStatement()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.Join([]string{
+		"WITH CLUSTERING ORDER BY (created DESC)",
+		"AND comment = 'User''s events'",
+		"AND compaction = {'class': 'TimeWindowCompactionStrategy', 'compaction_window_size': '1', 'compaction_window_unit': 'DAYS'}",
+		"AND default_time_to_live = 604800",
+		"AND gc_grace_seconds = 0",
+		";",
+	}, "\n")
+	if !strings.HasSuffix(stmt, ")\n"+expected) {
+		t.Fatal(stmt)
+	}
+
+	// Properties are merged, the compressor sets the class of the compression unless the properties do
+	tbl = tbl.WithOptions(Options{
+		Compressor: "LZ4Compressor",
+		Properties: &TableProperties{
+			Compression: map[string]string{"chunk_length_in_kb": "64"},
+			Caching:     map[string]string{"keys": "ALL", "rows_per_partition": "NONE"},
+			Comment:     "Events",
+		},
+	})
+	if err := tbl.(TableChanger).Alter(); err != nil {
+		t.Fatal(err)
+	}
+	expectedAlter := "ALTER TABLE ks.events WITH caching = {'keys': 'ALL', 'rows_per_partition': 'NONE'} AND comment = 'Events' " +
+		"AND compaction = {'class': 'TimeWindowCompactionStrategy', 'compaction_window_size': '1', 'compaction_window_unit': 'DAYS'} " +
+		"AND compression = {'chunk_length_in_kb': '64', 'class': 'LZ4Compressor'} AND default_time_to_live = 604800 AND gc_grace_seconds = 0"
+	if len(stmts) != 1 || stmts[0] != expectedAlter {
+		t.Fatal(stmts)
+	}
+
+	plain := NewConnection(qe).KeySpace("ks").Table("events", Event{}, Keys{PartitionKeys: []string{"UserId"}})
+	if _, err := plain.(TableChanger).AlterStatement(); err == nil {
+		t.Fatal("Expected an error altering a table without properties")
+	}
+	stmt, err = plain.WithOptions(Options{CompactStorage: true, Compressor: "SnappyCompressor"}).CreateStatement()
+	if err != nil || !strings.HasSuffix(stmt, ")\nWITH COMPACT STORAGE\nAND compression = {'class': 'SnappyCompressor'}\n;") {
+		t.Fatal(stmt, err)
+	}
+}
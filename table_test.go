@@ -1,8 +1,10 @@
 package gocassa
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -279,8 +281,17 @@ type OptionCheckingQE struct {
 }
 
 func (qe OptionCheckingQE) QueryWithOptions(opts Options, stmt string, params ...interface{}) ([]map[string]interface{}, error) {
+	return qe.QueryContext(context.Background(), opts, stmt, params...)
+}
+
+func (qe OptionCheckingQE) QueryContext(ctx context.Context, opts Options, stmt string, params ...interface{}) ([]map[string]interface{}, error) {
+	qe.opts.Consistency = opts.Consistency
+	return []map[string]interface{}{}, ctx.Err()
+}
+
+func (qe OptionCheckingQE) QueryIter(ctx context.Context, opts Options, stmt string, params ...interface{}) RowIterator {
 	qe.opts.Consistency = opts.Consistency
-	return []map[string]interface{}{}, nil
+	return &sliceRowIterator{err: ctx.Err()}
 }
 
 func (qe OptionCheckingQE) Query(stmt string, params ...interface{}) ([]map[string]interface{}, error) {
@@ -288,8 +299,17 @@ func (qe OptionCheckingQE) Query(stmt string, params ...interface{}) ([]map[stri
 }
 
 func (qe OptionCheckingQE) ExecuteWithOptions(opts Options, stmt string, params ...interface{}) error {
+	return qe.ExecuteContext(context.Background(), opts, stmt, params...)
+}
+
+func (qe OptionCheckingQE) ExecuteContext(ctx context.Context, opts Options, stmt string, params ...interface{}) error {
 	qe.opts.Consistency = opts.Consistency
-	return nil
+	return ctx.Err()
+}
+
+func (qe OptionCheckingQE) ExecuteCAS(ctx context.Context, opts Options, current map[string]interface{}, stmt string, params ...interface{}) (bool, error) {
+	qe.opts.Consistency = opts.Consistency
+	return true, ctx.Err()
 }
 
 func (qe OptionCheckingQE) Execute(stmt string, params ...interface{}) error {
@@ -297,12 +317,40 @@ func (qe OptionCheckingQE) Execute(stmt string, params ...interface{}) error {
 }
 
 func (qe OptionCheckingQE) ExecuteAtomically(stmt []string, params [][]interface{}) error {
-	return nil
+	return qe.ExecuteAtomicallyContext(context.Background(), stmt, params)
+}
+
+func (qe OptionCheckingQE) ExecuteAtomicallyContext(ctx context.Context, stmt []string, params [][]interface{}) error {
+	return qe.ExecuteBatch(ctx, LoggedBatch, stmt, params)
+}
+
+func (qe OptionCheckingQE) ExecuteBatch(ctx context.Context, batchType BatchType, stmt []string, params [][]interface{}) error {
+	return ctx.Err()
 }
 
 func (qe OptionCheckingQE) Close() {
 }
 
+type sliceRowIterator struct {
+	rows []map[string]interface{}
+	err  error
+}
+
+func (it *sliceRowIterator) MapScan(m map[string]interface{}) bool {
+	if it.err != nil || len(it.rows) == 0 {
+		return false
+	}
+	for k, v := range it.rows[0] {
+		m[k] = v
+	}
+	it.rows = it.rows[1:]
+	return true
+}
+
+func (it *sliceRowIterator) Close() error {
+	return it.err
+}
+
 func TestQueryWithConsistency(t *testing.T) {
 	// It's tricky to verify this against a live DB, so mock out the
 	// query executor and make sure the right options get passed
@@ -354,6 +402,327 @@ func TestExecuteWithConsistency(t *testing.T) {
 	}
 }
 
+func TestRunContextCancelled(t *testing.T) {
+	resultOpts := Options{}
+	qe := OptionCheckingQE{opts: &resultOpts}
+	conn := &connection{q: qe}
+	ks := conn.KeySpace("some ks")
+	cs := ks.Table("customerWithContext", Customer{}, Keys{PartitionKeys: []string{"Id"}})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	res := &[]Customer{}
+	if err := cs.Where(Eq("Id", 1)).Read(res).RunContext(ctx); err != context.Canceled {
+		t.Fatal(fmt.Sprint("Expected error:", context.Canceled, "got:", err))
+	}
+	op := cs.Set(Customer{Id: "100", Name: "Joe"}).Add(cs.Where(Eq("Id", "101")).Delete())
+	if err := op.RunContext(ctx); err != context.Canceled {
+		t.Fatal(fmt.Sprint("Expected error:", context.Canceled, "got:", err))
+	}
+	if err := op.RunAtomicallyContext(ctx); err != context.Canceled {
+		t.Fatal(fmt.Sprint("Expected error:", context.Canceled, "got:", err))
+	}
+	if err := op.RunContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConditionalStatements(t *testing.T) {
+	qe := OptionCheckingQE{opts: &Options{}}
+	conn := &connection{q: qe}
+	ks := conn.KeySpace("ks")
+	cs := ks.Table("customer", Customer{}, Keys{PartitionKeys: []string{"Id"}}).WithOptions(Options{TableName: "customer"})
+
+	stmt, vals := cs.SetIfNotExists(Customer{Id: "1", Name: "Joe"}, nil).WithOptions(Options{TTL: time.Minute}).GenerateStatement()
+	if !strings.HasSuffix(stmt, "VALUES (?, ?) IF NOT EXISTS USING TTL ?") || !reflect.DeepEqual(vals, []interface{}{"1", "Joe", 60}) {
+		t.Fatal(stmt, vals)
+	}
+
+	stmt, vals = cs.Where(Eq("Id", "1")).UpdateIf(nil, map[string]interface{}{"Name": "Jim"}, nil).GenerateStatement()
+	if stmt != "UPDATE ks.customer SET Name = ? WHERE id = ? IF EXISTS" || !reflect.DeepEqual(vals, []interface{}{"Jim", "1"}) {
+		t.Fatal(stmt, vals)
+	}
+
+	conditions := []Relation{Eq("Name", "Joe"), In("Name", "Joe", "Jim")}
+	stmt, vals = cs.Where(Eq("Id", "1")).UpdateIf(conditions, map[string]interface{}{"Name": "Jim"}, nil).GenerateStatement()
+	if stmt != "UPDATE ks.customer SET Name = ? WHERE id = ? IF name = ? AND name IN ?" ||
+		!reflect.DeepEqual(vals, []interface{}{"Jim", "1", "Joe", []interface{}{"Joe", "Jim"}}) {
+		t.Fatal(stmt, vals)
+	}
+
+	stmt, vals = cs.Where(Eq("Id", "1")).DeleteIf(conditions[:1], nil).GenerateStatement()
+	if stmt != "DELETE FROM ks.customer WHERE id = ? IF name = ?" || !reflect.DeepEqual(vals, []interface{}{"1", "Joe"}) {
+		t.Fatal(stmt, vals)
+	}
+
+	result := &CASResult{}
+	if err := cs.Where(Eq("Id", "1")).DeleteIf(nil, result).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !result.Applied {
+		t.Fatal("Expected conditional delete to be applied")
+	}
+
+	// A conditional write run as a batch on its own still fills its result, it can not be batched with other ops
+	result = &CASResult{}
+	if err := Noop().Add(cs.SetIfNotExists(Customer{Id: "1", Name: "Joe"}, result)).RunAtomically(); err != nil || !result.Applied {
+		t.Fatal(result, err)
+	}
+	err := cs.SetIfNotExists(Customer{Id: "1", Name: "Joe"}, nil).Add(cs.Where(Eq("Id", "2")).Delete()).RunAtomically()
+	if err == nil {
+		t.Fatal("Expected batching a conditional write with other ops to fail")
+	}
+}
+
+func TestTTLStatements(t *testing.T) {
+	qe := OptionCheckingQE{opts: &Options{}}
+	conn := &connection{q: qe}
+	ks := conn.KeySpace("ks")
+	cs := ks.Table("customer", Customer{}, Keys{PartitionKeys: []string{"Id"}}).WithOptions(Options{TableName: "customer"})
+
+	// TTLs are rounded to the second
+	for ttl, seconds := range map[time.Duration]int{900 * time.Millisecond: 1, 1500 * time.Millisecond: 2, time.Hour: 3600} {
+		stmt, vals := cs.Set(Customer{Id: "1", Name: "Joe"}).WithOptions(Options{TTL: ttl}).GenerateStatement()
+		if stmt != "UPDATE ks.customer USING TTL ? SET name = ? WHERE id = ?" || !reflect.DeepEqual(vals, []interface{}{seconds, "Joe", "1"}) {
+			t.Fatal(ttl, stmt, vals)
+		}
+	}
+	// Rather than never expiring
+	op := cs.Set(Customer{Id: "1", Name: "Joe"}).WithOptions(Options{TTL: 400 * time.Millisecond})
+	if err := op.Run(); err == nil {
+		t.Fatal("Expected a TTL rounding to zero to fail")
+	}
+	if err := op.Add(cs.Where(Eq("Id", "2")).Delete()).RunAtomically(); err == nil {
+		t.Fatal("Expected a TTL rounding to zero to fail in a batch")
+	}
+}
+
+func TestTimestampStatements(t *testing.T) {
+	qe := OptionCheckingQE{opts: &Options{}}
+	conn := &connection{q: qe}
+	ks := conn.KeySpace("ks")
+	ts := time.Unix(1136214245, 123456789)
+	cs := ks.Table("customer", Customer{}, Keys{PartitionKeys: []string{"Id"}}).WithOptions(Options{
+		TableName: "customer",
+		Timestamp: ts,
+	})
+
+	stmt, vals := cs.Set(Customer{Id: "1", Name: "Joe"}).GenerateStatement()
+	if stmt != "UPDATE ks.customer USING TIMESTAMP ? SET name = ? WHERE id = ?" ||
+		!reflect.DeepEqual(vals, []interface{}{int64(1136214245123456), "Joe", "1"}) {
+		t.Fatal(stmt, vals)
+	}
+
+	// Conditional writes can not have a custom timestamp
+	stmt, vals = cs.SetIfNotExists(Customer{Id: "1", Name: "Joe"}, nil).WithOptions(Options{TTL: time.Minute}).GenerateStatement()
+	if !strings.HasSuffix(stmt, "VALUES (?, ?) IF NOT EXISTS USING TTL ?") || !reflect.DeepEqual(vals, []interface{}{"1", "Joe", 60}) {
+		t.Fatal(stmt, vals)
+	}
+	stmt, vals = cs.Where(Eq("Id", "1")).UpdateIf([]Relation{Eq("Name", "Joe")}, map[string]interface{}{"Name": "Jim"}, nil).GenerateStatement()
+	if stmt != "UPDATE ks.customer SET Name = ? WHERE id = ? IF name = ?" || !reflect.DeepEqual(vals, []interface{}{"Jim", "1", "Joe"}) {
+		t.Fatal(stmt, vals)
+	}
+	stmt, vals = cs.Where(Eq("Id", "1")).DeleteIf(nil, nil).GenerateStatement()
+	if stmt != "DELETE FROM ks.customer WHERE id = ? IF EXISTS" || !reflect.DeepEqual(vals, []interface{}{"1"}) {
+		t.Fatal(stmt, vals)
+	}
+
+	// Op level options take precedence, deletes do not accept a TTL
+	stmt, vals = cs.Where(Eq("Id", "1")).Delete().WithOptions(Options{TTL: time.Minute, Timestamp: ts.Add(time.Second)}).GenerateStatement()
+	if stmt != "DELETE FROM ks.customer USING TIMESTAMP ? WHERE id = ?" ||
+		!reflect.DeepEqual(vals, []interface{}{int64(1136214246123456), "1"}) {
+		t.Fatal(stmt, vals)
+	}
+}
+
+func TestMetadataStatements(t *testing.T) {
+	type customerWithMetadata struct {
+		Id            string
+		Name          string
+		NameWriteTime int64 `cql:"writetime(Name)"`
+	}
+	qe := OptionCheckingQE{opts: &Options{}}
+	conn := &connection{q: qe}
+	ks := conn.KeySpace("ks")
+	cs := ks.Table("customer", customerWithMetadata{}, Keys{PartitionKeys: []string{"Id"}}).WithOptions(Options{
+		TableName: "customer",
+	})
+
+	stmt, _ := cs.Where(Eq("Id", "1")).Read(&[]customerWithMetadata{}).GenerateStatement()
+	if !strings.HasPrefix(stmt, "SELECT ") || !strings.Contains(stmt, ", writetime(name) FROM ks.customer ") {
+		t.Fatal(stmt)
+	}
+
+	// Metadata fields are not columns
+	stmt, _ = cs.Set(customerWithMetadata{Id: "1", Name: "Joe", NameWriteTime: 1}).GenerateStatement()
+	if stmt != "UPDATE ks.customer SET name = ? WHERE id = ?" {
+		t.Fatal(stmt)
+	}
+
+	stmt, _ = cs.Where(Eq("Id", "1")).Read(&[]customerWithMetadata{}).WithOptions(Options{
+		Select: []string{"name", "TTL(name)"},
+	}).GenerateStatement()
+	if !strings.HasPrefix(stmt, "SELECT name, TTL(name) FROM ks.customer ") {
+		t.Fatal(stmt)
+	}
+}
+
+type Event struct {
+	UserId  string    `cql:"user_id,partition"`
+	Created time.Time `cql:"created,clustering,desc"`
+	Id      string    `cql:"id,clustering"`
+	Owner   string    `cql:"owner,static"`
+	Payload string    `cql:"payload"`
+}
+
+func TestTableFromStruct(t *testing.T) {
+	qe := OptionCheckingQE{opts: &Options{}}
+	conn := &connection{q: qe}
+	ks := conn.KeySpace("ks")
+
+	tbl, err := ks.TableFromStruct("events", Event{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tbl.Name() != "events__user_id__created_id" {
+		t.Fatal(tbl.Name())
+	}
+	stmt, err := tbl.CreateStatement()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"    owner varchar STATIC",
+		"    PRIMARY KEY ((user_id), created, id)",
+		"WITH CLUSTERING ORDER BY (created DESC, id ASC)",
+	} {
+		if !strings.Contains(stmt, s) {
+			t.Fatalf("Expected %q in %s", s, stmt)
+		}
+	}
+
+	for _, row := range []interface{}{
+		1,
+		struct{ Id string }{},
+		struct {
+			Id string `cql:"id,partition,clustering"`
+		}{},
+		struct {
+			Id      string `cql:"id,partition"`
+			Created string `cql:"created,desc"`
+		}{},
+		struct {
+			Id      string `cql:"id,partition"`
+			Created string `cql:"created,clustering,asc,desc"`
+		}{},
+		struct {
+			Id    string `cql:"id,partition"`
+			Owner string `cql:"owner,static"`
+		}{},
+		struct {
+			Id string `cql:"id,primary"`
+		}{},
+	} {
+		if _, err := ks.TableFromStruct("invalid", row); err == nil {
+			t.Errorf("Expected an error for %#v", row)
+		}
+	}
+}
+
+func TestSyncStatements(t *testing.T) {
+	live := func(rows ...map[string]interface{}) KeySpace {
+		return NewConnection(RowsQE{OptionCheckingQE: OptionCheckingQE{opts: &Options{}}, rows: rows}).KeySpace("ks")
+	}
+	columns := []map[string]interface{}{
+		schemaRow("user_id", "partition_key", 0, "none", "text"),
+		schemaRow("created", "clustering", 0, "desc", "timestamp"),
+		schemaRow("id", "clustering", 1, "asc", "text"),
+		schemaRow("owner", "static", -1, "none", "text"),
+	}
+
+	// Missing tables are created
+	tbl, err := live().TableFromStruct("events", Event{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmts, err := tbl.SyncStatements()
+	if err != nil || len(stmts) != 1 || !strings.HasPrefix(stmts[0], "CREATE TABLE IF NOT EXISTS ks.events__user_id__created_id (") {
+		t.Fatal(stmts, err)
+	}
+
+	// Missing columns are added
+	tbl, _ = live(columns...).TableFromStruct("events", Event{})
+	stmts, err = tbl.SyncStatements()
+	if err != nil || !reflect.DeepEqual(stmts, []string{"ALTER TABLE ks.events__user_id__created_id ADD payload text"}) {
+		t.Fatal(stmts, err)
+	}
+	tbl, _ = live(append(columns, schemaRow("payload", "regular", -1, "none", "text"))...).TableFromStruct("events", Event{})
+	if stmts, err = tbl.SyncStatements(); err != nil || len(stmts) != 0 {
+		t.Fatal(stmts, err)
+	}
+
+	// Anything else is an error
+	for _, changed := range [][]map[string]interface{}{
+		append(columns, schemaRow("payload", "regular", -1, "none", "int")),
+		append(columns[:3:3], schemaRow("owner", "regular", -1, "none", "text")),
+		append(columns[1:2:2], schemaRow("user_id", "clustering", 0, "asc", "text")),
+		append(columns[:1:1], schemaRow("created", "clustering", 0, "asc", "timestamp"), columns[2]),
+		append(columns[:1:1], schemaRow("created", "regular", -1, "none", "timestamp"), columns[2]),
+	} {
+		tbl, _ = live(changed...).TableFromStruct("events", Event{})
+		if stmts, err := tbl.SyncStatements(); err == nil {
+			t.Errorf("Expected an error for %v but got %v", changed, stmts)
+		}
+	}
+}
+
+// Mock QueryExecutor that keeps track of the batches executed through it
+type BatchRecordingQE struct {
+	OptionCheckingQE
+	batchTypes []BatchType
+	stmts      [][]string
+}
+
+func (qe *BatchRecordingQE) ExecuteBatch(ctx context.Context, batchType BatchType, stmts []string, params [][]interface{}) error {
+	qe.batchTypes = append(qe.batchTypes, batchType)
+	qe.stmts = append(qe.stmts, stmts)
+	return nil
+}
+
+func TestRunBatch(t *testing.T) {
+	qe := &BatchRecordingQE{OptionCheckingQE: OptionCheckingQE{opts: &Options{}}}
+	conn := &connection{q: qe}
+	ks := conn.KeySpace("ks")
+	cs := ks.Table("customer", Customer{}, Keys{PartitionKeys: []string{"Id"}})
+
+	op := cs.Set(Customer{Id: "1", Name: "Joe"}).Add(cs.Set(Customer{Id: "2", Name: "Jim"}))
+	if err := op.RunAtomically(); err != nil {
+		t.Fatal(err)
+	}
+	if err := op.RunBatch(UnloggedBatch); err != nil {
+		t.Fatal(err)
+	}
+	if err := op.RunBatchContext(context.Background(), CounterBatch); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(qe.batchTypes, []BatchType{LoggedBatch, UnloggedBatch, CounterBatch}) {
+		t.Fatal(qe.batchTypes)
+	}
+	for _, stmts := range qe.stmts {
+		if len(stmts) != 2 {
+			t.Fatal(stmts)
+		}
+	}
+
+	// Nothing to batch
+	if err := Noop().RunBatch(UnloggedBatch); err != nil {
+		t.Fatal(err)
+	}
+	if len(qe.batchTypes) != 3 {
+		t.Fatal(qe.batchTypes)
+	}
+}
+
 func validateTableName(t *testing.T, tbl TableChanger, expected string) bool {
 	ok := tbl.Name() == expected
 	if !ok {
@@ -0,0 +1,82 @@
+package gocassa
+
+import (
+	"strings"
+)
+
+// tableGroup is the TableChanger of recipes made of several tables, which are created, synced and altered together
+type tableGroup struct {
+	name   string
+	tables []TableChanger
+}
+
+func (g tableGroup) each(f func(TableChanger) error) error {
+	for _, t := range g.tables {
+		if err := f(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g tableGroup) statements(f func(TableChanger) (string, error), sep string) (string, error) {
+	stmts := make([]string, len(g.tables))
+	for i, t := range g.tables {
+		stmt, err := f(t)
+		if err != nil {
+			return "", err
+		}
+		stmts[i] = stmt
+	}
+	return strings.Join(stmts, sep), nil
+}
+
+func (g tableGroup) Create() error {
+	return g.each(TableChanger.Create)
+}
+
+// CreateStatement returns the statements creating the tables, one after the other
+func (g tableGroup) CreateStatement() (string, error) {
+	return g.statements(TableChanger.CreateStatement, "\n")
+}
+
+func (g tableGroup) CreateIfNotExist() error {
+	return g.each(TableChanger.CreateIfNotExist)
+}
+
+func (g tableGroup) CreateIfNotExistStatement() (string, error) {
+	return g.statements(TableChanger.CreateIfNotExistStatement, "\n")
+}
+
+func (g tableGroup) Recreate() error {
+	return g.each(TableChanger.Recreate)
+}
+
+func (g tableGroup) SyncStatements() ([]string, error) {
+	ret := []string{}
+	for _, t := range g.tables {
+		stmts, err := t.SyncStatements()
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, stmts...)
+	}
+	return ret, nil
+}
+
+func (g tableGroup) Sync() error {
+	return g.each(TableChanger.Sync)
+}
+
+// AlterStatement returns the statements altering the tables, separated by semicolons
+func (g tableGroup) AlterStatement() (string, error) {
+	return g.statements(TableChanger.AlterStatement, ";\n")
+}
+
+func (g tableGroup) Alter() error {
+	return g.each(TableChanger.Alter)
+}
+
+func (g tableGroup) Name() string {
+	return g.name
+}
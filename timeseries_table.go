@@ -46,6 +46,14 @@ func (o *timeSeriesT) Read(timeStamp time.Time, id, pointer interface{}) Op {
 }
 
 func (o *timeSeriesT) List(startTime time.Time, endTime time.Time, pointerToASlice interface{}) Op {
+	return o.list(startTime, endTime).Read(pointerToASlice)
+}
+
+func (o *timeSeriesT) ListIter(startTime time.Time, endTime time.Time) Iterator {
+	return o.list(startTime, endTime).Iter()
+}
+
+func (o *timeSeriesT) list(startTime time.Time, endTime time.Time) Filter {
 	buckets := []interface{}{}
 	start := o.bucket(startTime.Unix())
 	for i := start; ; i += int64(o.bucketSize/time.Second) * 1000 {
@@ -54,7 +62,7 @@ func (o *timeSeriesT) List(startTime time.Time, endTime time.Time, pointerToASli
 		}
 		buckets = append(buckets, i)
 	}
-	return o.Where(In(bucketFieldName, buckets...), GTE(o.timeField, startTime), LTE(o.timeField, endTime)).Read(pointerToASlice)
+	return o.Where(In(bucketFieldName, buckets...), GTE(o.timeField, startTime), LTE(o.timeField, endTime))
 }
 
 func (o *timeSeriesT) WithOptions(opt Options) TimeSeriesTable {
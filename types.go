@@ -0,0 +1,234 @@
+package gocassa
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	r "github.com/gocassa/gocassa/reflect"
+)
+
+// Tag options changing the type of the column a field is stored in, eg.
+//
+//	Tags     []string   `cql:"tags,set"`          // set<varchar> rather than list<varchar>
+//	Position Point      `cql:"position,tuple"`    // tuple<double, double> rather than frozen<point>
+//	History  []float64  `cql:"history,frozen"`    // frozen<list<double>>
+//	Id       gocql.UUID `cql:"id,type=timeuuid"`  // timeuuid rather than uuid
+//
+// Collections nested in collections, tuples or user defined types are always frozen, eg. a map[string][]int is
+// stored in a map<varchar, frozen<list<int>>> column.
+const (
+	// setOption stores slices and arrays as sets rather than lists
+	setOption = "set"
+	// tupleOption stores structs as tuples of their fields, in the order they are declared in, and arrays as tuples
+	// of their elements
+	tupleOption = "tuple"
+	// frozenOption freezes the column, which is then written and read as a whole
+	frozenOption = "frozen"
+	// typeOption prefixes the CQL type of the column, overriding the one derived from the type of the field. It
+	// is mostly useful for types having several representations, eg. "type=timeuuid", "type=date", "type=tinyint"
+	// or "type=duration".
+	typeOption = "type="
+)
+
+// fieldOptions returns the tag options of the fields of a struct, by lower cased field name. Fields without options
+// are left out.
+func fieldOptions(entity interface{}) map[string][]string {
+	options := map[string][]string{}
+	for _, field := range r.TaggedFields(entity) {
+		if len(field.Options) > 0 {
+			options[strings.ToLower(field.Name)] = field.Options
+		}
+	}
+	return options
+}
+
+// columnTypeOf returns the CQL type of the column a value is stored in, given the tag options of its field
+func columnTypeOf(value interface{}, options []string) (string, error) {
+	var typ string
+	var err error
+	switch t := reflect.TypeOf(value); {
+	case typeOverride(options) != "":
+		typ = typeOverride(options)
+	case containsString(options, tupleOption):
+		typ, err = tupleTypeOf(t)
+	case containsString(options, setOption):
+		if t == nil || (t.Kind() != reflect.Slice && t.Kind() != reflect.Array) {
+			return "", fmt.Errorf("Unsupported type %T, sets must be slices or arrays", value)
+		}
+		typ, err = elemTypeOf(t.Elem())
+		typ = fmt.Sprintf("set<%s>", typ)
+	default:
+		typ, err = stringTypeOf(value)
+	}
+	if err != nil {
+		return "", err
+	}
+	if containsString(options, frozenOption) && !strings.HasPrefix(typ, "frozen<") {
+		typ = fmt.Sprintf("frozen<%s>", typ)
+	}
+	return typ, nil
+}
+
+// typeOverride returns the type set with the type option, if any
+func typeOverride(options []string) string {
+	for _, opt := range options {
+		if strings.HasPrefix(opt, typeOption) {
+			return strings.TrimSpace(strings.TrimPrefix(opt, typeOption))
+		}
+	}
+	return ""
+}
+
+func tupleTypeOf(t reflect.Type) (string, error) {
+	elemTypes := []reflect.Type{}
+	switch {
+	case t != nil && t.Kind() == reflect.Array:
+		for i := 0; i < t.Len(); i++ {
+			elemTypes = append(elemTypes, t.Elem())
+		}
+	case t != nil && t.Kind() == reflect.Struct && t != timeType:
+		for _, f := range tupleFields(t) {
+			elemTypes = append(elemTypes, f.Type)
+		}
+	}
+	if len(elemTypes) == 0 {
+		return "", fmt.Errorf("Unsupported type %v, tuples must be structs or arrays", t)
+	}
+	types := make([]string, len(elemTypes))
+	for i, elemType := range elemTypes {
+		typ, err := elemTypeOf(elemType)
+		if err != nil {
+			return "", err
+		}
+		types[i] = typ
+	}
+	return fmt.Sprintf("tuple<%s>", strings.Join(types, ", ")), nil
+}
+
+// tupleFields returns the fields of a struct stored as a tuple, in the order they are declared in
+func tupleFields(t reflect.Type) []reflect.StructField {
+	fields := []reflect.StructField{}
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); f.PkgPath == "" && f.Tag.Get(r.TagName) != "-" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// columnValue converts a value into the one stored in its column, given the tag options of its field: structs
+// stored as tuples are converted into the list of their fields
+func columnValue(value interface{}, options []string) interface{} {
+	if !containsString(options, tupleOption) {
+		return value
+	}
+	v := reflect.Indirect(reflect.ValueOf(value))
+	if v.Kind() != reflect.Struct {
+		return value
+	}
+	fields := tupleFields(v.Type())
+	elems := make([]interface{}, len(fields))
+	for i, f := range fields {
+		elems[i] = v.FieldByIndex(f.Index).Interface()
+	}
+	return elems
+}
+
+// decodeTupleHook decodes tuples into structs. Tuples are read as lists of their elements by the mock keyspace, and
+// as one column per element by gocql, eg. "position[0]" and "position[1]", which are gathered into lists first.
+func decodeTupleHook(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+	switch d := data.(type) {
+	case map[string]interface{}:
+		return gatherTupleColumns(d), nil
+	case []interface{}:
+		if t.Kind() != reflect.Struct || t == timeType {
+			return data, nil
+		}
+		fields := tupleFields(t)
+		if len(fields) != len(d) {
+			return nil, fmt.Errorf("Can not decode a tuple of %d elements into %v", len(d), t)
+		}
+		m := make(map[string]interface{}, len(d))
+		for i, f := range fields {
+			name := strings.Split(f.Tag.Get(r.TagName), ",")[0]
+			if name == "" {
+				name = f.Name
+			}
+			m[name] = d[i]
+		}
+		return m, nil
+	}
+	return data, nil
+}
+
+func gatherTupleColumns(m map[string]interface{}) map[string]interface{} {
+	var tuples map[string][]interface{}
+	for k, v := range m {
+		open := strings.LastIndex(k, "[")
+		if open <= 0 || !strings.HasSuffix(k, "]") {
+			continue
+		}
+		i, err := strconv.Atoi(k[open+1 : len(k)-1])
+		if err != nil || i < 0 {
+			continue
+		}
+		if tuples == nil {
+			tuples = map[string][]interface{}{}
+		}
+		name := k[:open]
+		for len(tuples[name]) <= i {
+			tuples[name] = append(tuples[name], nil)
+		}
+		tuples[name][i] = v
+	}
+	if tuples == nil {
+		return m
+	}
+	ret := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		ret[k] = v
+	}
+	for name, elems := range tuples {
+		for i := range elems {
+			delete(ret, fmt.Sprintf("%s[%d]", name, i))
+		}
+		ret[name] = elems
+	}
+	return ret
+}
+
+// setValue returns the elements of a set the way Cassandra stores them, ie. sorted and without duplicates. It is
+// used by the mock keyspace.
+func setValue(value interface{}) interface{} {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return value
+	}
+	elems := make([]interface{}, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		elems = append(elems, v.Index(i).Interface())
+	}
+	sort.SliceStable(elems, func(i, j int) bool {
+		less, err := builtinLessThan(elems[i], elems[j])
+		return err == nil && less
+	})
+	ret := make([]interface{}, 0, len(elems))
+	for _, e := range elems {
+		if !containsValue(ret, e) {
+			ret = append(ret, e)
+		}
+	}
+	return ret
+}
+
+func containsValue(s []interface{}, v interface{}) bool {
+	for _, sv := range s {
+		if reflect.DeepEqual(sv, v) {
+			return true
+		}
+	}
+	return false
+}
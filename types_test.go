@@ -0,0 +1,170 @@
+package gocassa
+
+import (
+	"math/big"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"gopkg.in/inf.v0"
+)
+
+type Point struct {
+	X float64
+	Y float64
+}
+
+type Route struct {
+	Id       string
+	Tags     []string         `cql:"tags,set"`
+	Position Point            `cql:"position,tuple"`
+	Bounds   [2]int           `cql:"bounds,tuple"`
+	History  []float64        `cql:"history,frozen"`
+	Legs     map[string][]int `cql:"legs"`
+	Stops    [][]string       `cql:"stops"`
+}
+
+type Reading struct {
+	Id       gocql.UUID     `cql:"id,partition,type=timeuuid"`
+	Sensor   gocql.UUID     `cql:"sensor"`
+	Day      time.Time      `cql:"day,type=date"`
+	At       time.Duration  `cql:"at,type=time"`
+	Interval time.Duration  `cql:"interval,type=duration"`
+	Timeout  time.Duration  `cql:"timeout"`
+	Period   gocql.Duration `cql:"period"`
+	Address  net.IP         `cql:"address"`
+	Value    *inf.Dec       `cql:"value"`
+	Count    *big.Int       `cql:"count"`
+	Small    int16          `cql:"small,type=smallint"`
+	Tiny     int8           `cql:"tiny,type=tinyint"`
+	Retries  int8           `cql:"retries"`
+	Unsigned uint32         `cql:"unsigned"`
+}
+
+func TestScalarColumnTypes(t *testing.T) {
+	ks := NewConnection(OptionCheckingQE{opts: &Options{}}).KeySpace("ks")
+	tbl, err := ks.TableFromStruct("readings", Reading{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt, err := tbl.WithOptions(Options{TableName: "readings"}).CreateStatement()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"    address inet",
+		"    at time",
+		"    count varint",
+		"    day date",
+		"    id timeuuid",
+		"    interval duration",
+		"    period duration",
+		"    retries varint",
+		"    sensor uuid",
+		"    small smallint",
+		"    tiny tinyint",
+		"    timeout bigint",
+		"    unsigned varint",
+		"    value decimal",
+	} {
+		if !strings.Contains(stmt, s) {
+			t.Fatalf("Expected %q in %s", s, stmt)
+		}
+	}
+
+	var r Reading
+	err = decodeResult(map[string]interface{}{
+		"interval": gocql.Duration{Days: 1, Nanoseconds: int64(time.Minute)},
+		"address":  net.ParseIP("10.0.0.1"),
+		"value":    inf.NewDec(314, 2),
+		"count":    big.NewInt(42),
+		"small":    int16(-3),
+		"tiny":     int8(7),
+		"unsigned": big.NewInt(9),
+	}, &r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Interval != 24*time.Hour+time.Minute || !r.Address.Equal(net.ParseIP("10.0.0.1")) || r.Value.String() != "3.14" ||
+		r.Count.Int64() != 42 || r.Small != -3 || r.Tiny != 7 || r.Unsigned != 9 {
+		t.Fatalf("%+v", r)
+	}
+	if err := decodeResult(map[string]interface{}{"interval": gocql.Duration{Months: 1}}, &r); err == nil {
+		t.Fatal("Expected an error decoding months into a time.Duration")
+	}
+}
+
+func TestColumnTypes(t *testing.T) {
+	tbl := NewConnection(OptionCheckingQE{opts: &Options{}}).KeySpace("ks").Table("routes", Route{}, Keys{PartitionKeys: []string{"Id"}}).WithOptions(Options{
+		TableName: "routes",
+	})
+	stmt, err := tbl.CreateStatement()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"    bounds tuple<int, int>",
+		"    history frozen<list<double>>",
+		"    legs map<varchar, frozen<list<int>>>",
+		"    position tuple<double, double>",
+		"    stops list<frozen<list<varchar>>>",
+		"    tags set<varchar>",
+	} {
+		if !strings.Contains(stmt, s) {
+			t.Fatalf("Expected %q in %s", s, stmt)
+		}
+	}
+
+	for _, c := range []struct {
+		value   interface{}
+		options []string
+	}{
+		{"a", []string{setOption}},
+		{[]int{}, []string{tupleOption}},
+		{struct{}{}, []string{tupleOption}},
+	} {
+		if typ, err := columnTypeOf(c.value, c.options); err == nil {
+			t.Errorf("Expected an error for %#v with %v, got %s", c.value, c.options, typ)
+		}
+	}
+}
+
+func TestTupleValues(t *testing.T) {
+	tbl := NewConnection(OptionCheckingQE{opts: &Options{}}).KeySpace("ks").Table("routes", Route{}, Keys{PartitionKeys: []string{"Id"}}).WithOptions(Options{
+		TableName: "routes",
+	})
+	_, vals := tbl.Where(Eq("Id", "1")).Update(map[string]interface{}{
+		"Position": Point{X: 1, Y: 2},
+		"Tags":     SetAdd("a"),
+	}).GenerateStatement()
+	if !reflect.DeepEqual(vals, []interface{}{[]interface{}{1.0, 2.0}, []interface{}{"a"}, "1"}) {
+		t.Fatalf("Unexpected values %#v", vals)
+	}
+
+	// gocql returns the elements of tuples as separate columns
+	m := gatherTupleColumns(map[string]interface{}{"id": "1", "position[0]": 1.0, "position[1]": 2.0})
+	if !reflect.DeepEqual(m, map[string]interface{}{"id": "1", "position": []interface{}{1.0, 2.0}}) {
+		t.Fatal(m)
+	}
+	var r Route
+	if err := decodeResult(m, &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Id != "1" || r.Position != (Point{X: 1, Y: 2}) {
+		t.Fatal(r)
+	}
+}
+
+func TestSetModifiers(t *testing.T) {
+	for expected, mod := range map[string]Modifier{
+		"tags = tags + ?": SetAdd("a"),
+		"tags = tags - ?": SetRemove("a"),
+	} {
+		if cql, vals := mod.cql("tags"); cql != expected || !reflect.DeepEqual(vals, []interface{}{[]interface{}{"a"}}) {
+			t.Errorf("Expected %s but got %s %v", expected, cql, vals)
+		}
+	}
+}
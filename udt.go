@@ -0,0 +1,232 @@
+package gocassa
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
+	"gopkg.in/inf.v0"
+)
+
+// Struct fields, other than time.Time ones, are stored as user defined types (UDTs). The type is named after the
+// lower cased name of the Go type, and its fields are derived from the struct the same way the columns of a table
+// are, eg. a field of type
+//
+//	type Address struct {
+//		Street string
+//		City   string `cql:"city"`
+//	}
+//
+// is stored in a frozen<address> column, once the type is created with
+//
+//	CREATE TYPE IF NOT EXISTS keyspace.address (
+//	    city varchar,
+//	    street varchar
+//	)
+//
+// Tables create the types of their fields along with themselves, see also KeySpace.CreateType.
+
+var (
+	timeType         = reflect.TypeOf(time.Time{})
+	modifierType     = reflect.TypeOf(Modifier{})
+	interfaceType    = reflect.TypeOf((*interface{})(nil)).Elem()
+	marshalerType    = reflect.TypeOf((*gocql.Marshaler)(nil)).Elem()
+	udtMarshalerType = reflect.TypeOf((*gocql.UDTMarshaler)(nil)).Elem()
+	bigIntType       = reflect.TypeOf(big.Int{})
+	decType          = reflect.TypeOf(inf.Dec{})
+	durationType     = reflect.TypeOf(gocql.Duration{})
+)
+
+// isUDT returns whether values of the type are stored as user defined types, ie. it is a struct (or a pointer to
+// one) which gocql does not know how to marshal otherwise
+func isUDT(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t {
+	case timeType, modifierType, bigIntType, decType, durationType:
+		return false
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for _, typ := range []reflect.Type{t, reflect.PtrTo(t)} {
+		if typ.Implements(marshalerType) || typ.Implements(udtMarshalerType) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsUDT returns whether values of the type may hold user defined types
+func containsUDT(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Ptr, reflect.Struct:
+		return isUDT(t)
+	case reflect.Slice, reflect.Array:
+		return containsUDT(t.Elem())
+	case reflect.Map:
+		return containsUDT(t.Key()) || containsUDT(t.Elem())
+	}
+	return false
+}
+
+func udtName(t reflect.Type) (string, error) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Name() == "" {
+		return "", fmt.Errorf("Unsupported type %v, user defined types must be named", t)
+	}
+	return strings.ToLower(t.Name()), nil
+}
+
+// udtStringType returns the column type of a user defined type, which is frozen so it can be used in collections
+// and keys
+func udtStringType(t reflect.Type) (string, error) {
+	name, err := udtName(t)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("frozen<%s>", name), nil
+}
+
+// collectUDTs appends the user defined types values of the type may hold to udts, after the ones they depend on
+func collectUDTs(t reflect.Type, udts []reflect.Type) []reflect.Type {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return collectUDTs(t.Elem(), udts)
+	case reflect.Slice, reflect.Array:
+		return collectUDTs(t.Elem(), udts)
+	case reflect.Map:
+		return collectUDTs(t.Elem(), collectUDTs(t.Key(), udts))
+	case reflect.Struct:
+		if !isUDT(t) {
+			return udts
+		}
+		for _, udt := range udts {
+			if udt == t {
+				return udts
+			}
+		}
+		_, values := udtFields(t)
+		for _, v := range values {
+			udts = collectUDTs(reflect.TypeOf(v), udts)
+		}
+		return append(udts, t)
+	}
+	return udts
+}
+
+func udtFields(t reflect.Type) ([]string, []interface{}) {
+	m, _ := toMap(reflect.Zero(t).Interface())
+	return keyValues(m)
+}
+
+func createTypeStmt(keySpace string, t reflect.Type) (string, error) {
+	name, err := udtName(t)
+	if err != nil {
+		return "", err
+	}
+	fields, values := udtFields(t)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("User defined type %s has no fields", name)
+	}
+	options := fieldOptions(reflect.Zero(t).Interface())
+	fieldLines := make([]string, len(fields))
+	for i, field := range fields {
+		typeStr, err := columnTypeOf(values[i], options[strings.ToLower(field)])
+		if err != nil {
+			return "", err
+		}
+		fieldLines[i] = "    " + strings.ToLower(field) + " " + typeStr
+	}
+	return fmt.Sprintf("CREATE TYPE IF NOT EXISTS %s.%s (\n%s\n)", keySpace, name, strings.Join(fieldLines, ",\n")), nil
+}
+
+// createTypeStmts returns the statements creating the user defined types the values hold, in the order they have to
+// be created in
+func createTypeStmts(keySpace string, values []interface{}) ([]string, error) {
+	udts := []reflect.Type{}
+	for _, v := range values {
+		if v != nil {
+			udts = collectUDTs(reflect.TypeOf(v), udts)
+		}
+	}
+	stmts := make([]string, len(udts))
+	for i, udt := range udts {
+		stmt, err := createTypeStmt(keySpace, udt)
+		if err != nil {
+			return nil, err
+		}
+		stmts[i] = stmt
+	}
+	return stmts, nil
+}
+
+// udtValue converts the user defined types the value holds into maps keyed by lower cased field names, as structs
+// are marshalled by gocql based on the exact names of their fields. The mock keyspace stores them the same way, so
+// they are read back as they would be from Cassandra.
+func udtValue(v interface{}) interface{} {
+	switch v.(type) {
+	case nil, string, int, int64, float64, bool, time.Time, []byte:
+		return v
+	}
+	if !containsUDT(reflect.TypeOf(v)) {
+		return v
+	}
+	return udtReflectValue(reflect.ValueOf(v))
+}
+
+func udtReflectValue(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return udtValue(v.Elem().Interface())
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return udtReflectValue(v.Elem())
+	case reflect.Struct:
+		if !isUDT(v.Type()) {
+			return v.Interface()
+		}
+		m, _ := toMap(v.Interface())
+		ret := make(map[string]interface{}, len(m))
+		for k, fv := range m {
+			ret[strings.ToLower(k)] = udtValue(fv)
+		}
+		return ret
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		ret := make([]interface{}, v.Len())
+		for i := range ret {
+			ret[i] = udtReflectValue(v.Index(i))
+		}
+		return ret
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		ret := reflect.MakeMap(reflect.MapOf(v.Type().Key(), interfaceType))
+		for _, k := range v.MapKeys() {
+			elem := reflect.New(interfaceType).Elem()
+			if e := udtReflectValue(v.MapIndex(k)); e != nil {
+				elem.Set(reflect.ValueOf(e))
+			}
+			ret.SetMapIndex(k, elem)
+		}
+		return ret.Interface()
+	}
+	return v.Interface()
+}
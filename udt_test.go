@@ -0,0 +1,109 @@
+package gocassa
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type Location struct {
+	Lat float64
+	Lng float64
+}
+
+type Place struct {
+	Name     string
+	Location Location `cql:"loc"`
+}
+
+type Venue struct {
+	Id       string
+	Place    Place
+	Previous *Place
+	Others   []Place
+	Nearby   map[string]Location
+}
+
+func TestCreateTypeStatements(t *testing.T) {
+	ks := NewConnection(OptionCheckingQE{opts: &Options{}}).KeySpace("ks")
+	stmts, err := ks.CreateTypeStatements(Place{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"CREATE TYPE IF NOT EXISTS ks.location (\n    lat double,\n    lng double\n)",
+		"CREATE TYPE IF NOT EXISTS ks.place (\n    name varchar,\n    loc frozen<location>\n)",
+	}
+	if !reflect.DeepEqual(stmts, expected) {
+		t.Fatal(stmts)
+	}
+
+	for _, row := range []interface{}{nil, 1, struct{ Name string }{}, struct{}{}} {
+		if _, err := ks.CreateTypeStatements(row); err == nil {
+			t.Errorf("Expected an error for %#v", row)
+		}
+	}
+}
+
+func TestUDTStatements(t *testing.T) {
+	stmts := []string{}
+	qe := StatementRecordingQE{OptionCheckingQE: OptionCheckingQE{opts: &Options{}}, stmts: &stmts}
+	tbl := NewConnection(qe).KeySpace("ks").Table("venues", Venue{}, Keys{PartitionKeys: []string{"Id"}}).WithOptions(Options{
+		TableName: "venues",
+	})
+
+	stmt, err := tbl.CreateStatement()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"    nearby map<varchar, frozen<location>>",
+		"    others list<frozen<place>>",
+		"    place frozen<place>",
+		"    previous frozen<place>",
+	} {
+		if !strings.Contains(stmt, s) {
+			t.Fatalf("Expected %q in %s", s, stmt)
+		}
+	}
+
+	// The types are created before the table
+	if err := tbl.CreateIfNotExist(); err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) != 3 || !strings.HasPrefix(stmts[0], "CREATE TYPE IF NOT EXISTS ks.location") ||
+		!strings.HasPrefix(stmts[1], "CREATE TYPE IF NOT EXISTS ks.place") || !strings.HasPrefix(stmts[2], "CREATE TABLE IF NOT EXISTS ks.venues") {
+		t.Fatal(stmts)
+	}
+
+	// Values are marshalled as maps keyed by the names of the fields of the types
+	v := Venue{
+		Id:     "1",
+		Place:  Place{Name: "Hall", Location: Location{Lat: 1, Lng: 2}},
+		Others: []Place{{Name: "Annex"}},
+		Nearby: map[string]Location{"park": {Lat: 3}},
+	}
+	stmt, vals := tbl.Set(v).GenerateStatement()
+	if stmt != "UPDATE ks.venues SET nearby = ?, others = ?, place = ?, previous = ? WHERE id = ?" {
+		t.Fatal(stmt)
+	}
+	hall := map[string]interface{}{"name": "Hall", "loc": map[string]interface{}{"lat": 1.0, "lng": 2.0}}
+	annex := map[string]interface{}{"name": "Annex", "loc": map[string]interface{}{"lat": 0.0, "lng": 0.0}}
+	expected := []interface{}{
+		map[string]interface{}{"park": map[string]interface{}{"lat": 3.0, "lng": 0.0}},
+		[]interface{}{annex},
+		hall,
+		nil,
+		"1",
+	}
+	if !reflect.DeepEqual(vals, expected) {
+		t.Fatalf("Unexpected values %#v", vals)
+	}
+
+	_, vals = tbl.Where(Eq("Id", "1")).Update(map[string]interface{}{
+		"Others": ListAppend(v.Place),
+	}).GenerateStatement()
+	if !reflect.DeepEqual(vals, []interface{}{[]interface{}{hall}, "1"}) {
+		t.Fatalf("Unexpected values %#v", vals)
+	}
+}